@@ -2,6 +2,8 @@ package main
 
 import (
 	"flag"
+
+	Config "github.com/arush15june/chord-golang/src/pkg/config"
 )
 
 const (
@@ -21,9 +23,44 @@ const (
 	DefaultApiPort = "8090"
 )
 
+var defaults = Config.Default()
+
 var (
+	// ConfigFile is the path of a YAML or TOML config file. Flags override values from the file.
+	ConfigFile = flag.String("config", "", "Path of a YAML or TOML config file, keys are flag names. Flags override the file.")
+
+	// TLSCert is the certificate securing the RPC traffic.
+	TLSCert = flag.String("tlscert", defaults.TLSCert, "PEM certificate presented to peers, enables TLS for RPC traffic.")
+
+	// TLSKey is the key of TLSCert.
+	TLSKey = flag.String("tlskey", defaults.TLSKey, "PEM key of -tlscert.")
+
+	// TLSCA is the trust root of peer certificates.
+	TLSCA = flag.String("tlsca", defaults.TLSCA, "PEM CA certificates peers are verified against, system roots by default.")
+
+	// MutualTLS requires peers to authenticate.
+	MutualTLS = flag.Bool("mtls", defaults.MutualTLS, "Require peers to present certificates signed by -tlsca.")
+
+	// Identity binds VNode IDs to the TLS certificate.
+	Identity = flag.Bool("identity", defaults.Identity, "Derive VNode IDs from the -tlscert key and require peers to present identities signed by -tlsca.")
+
+	// RateLimit limits the requests served to a single peer.
+	RateLimit = flag.Float64("ratelimit", defaults.RateLimit, "Requests per second served to a single peer address over RPC and HTTP, 0 disables the limit.")
+
+	// GlobalRateLimit limits the requests served to all peers.
+	GlobalRateLimit = flag.Float64("globalratelimit", defaults.GlobalRateLimit, "Requests per second served to all peers together over RPC and HTTP, 0 disables the limit.")
+
+	// Burst is the burst allowed beyond the rate limits.
+	Burst = flag.Int("burst", defaults.Burst, "Requests served at once beyond -ratelimit and -globalratelimit.")
+
+	// MaxConns bounds concurrent connections.
+	MaxConns = flag.Int("maxconns", defaults.MaxConns, "Maximum concurrent RPC connections and HTTP requests, 0 disables the limit.")
+
+	// WalkFormat selects the output format of walk mode.
+	WalkFormat = flag.String("format", "json", "Output format of walk mode: 'json', 'dot' or 'svg'.")
+
 	// NodeMode selects the mode of the node to be create or join.
-	NodeMode = flag.String("mode", "create", "'join' or 'create'")
+	NodeMode = flag.String("mode", defaults.Mode, "'create', 'join' or 'walk' (print the ring topology reached through -rhost and exit)")
 	// NodeModeShort = flag.String("m")
 
 	// Workers selects the number of virtual nodes to create on the server.
@@ -31,13 +68,117 @@ var (
 	// WorkersShort = flag.Int("w")
 
 	// HostName is the hostname of the physical chord node.
-	HostName = flag.String("host", ":0", "Self hostname. Default: :0 (use <hostname>:0 for random port assignment")
+	HostName = flag.String("host", defaults.Host, "Self hostname. Default: :0 (use <hostname>:0 for random port assignment")
 	// HostNameShort = flag.String("h")
 
-	// RemoteHost is the hostname of an existing node in the chord network if join mode is selected.
-	RemoteHost = flag.String("rhost", "127.0.0.1:8000", "Remote chord node to join.")
+	// RemoteHost is a comma separated list of existing nodes in the chord network if join mode is selected.
+	RemoteHost = flag.String("rhost", defaults.RemoteHost, "Comma separated seed chord nodes to join, tried in order.")
 	// RemoteHostShort = flag.String("r")
 
+	// DiscoveryMethod selects how existing ring members are discovered.
+	DiscoveryMethod = flag.String("discovery", defaults.Discovery, "Seed discovery: 'none', 'mdns' (multicast DNS on the LAN) or 'file' (shared -discoverydir).")
+
+	// DiscoveryDir is the seed directory shared by processes using file discovery.
+	DiscoveryDir = flag.String("discoverydir", defaults.DiscoveryDir, "Shared seed directory for file discovery.")
+
+	// DiscoveryTimeout bounds waiting for mDNS answers.
+	DiscoveryTimeout = flag.Duration("discoverytimeout", defaults.DiscoveryTimeout, "Time to wait for mDNS answers.")
+
+	// JoinTimeout bounds the time spent joining the ring, after which the process exits.
+	JoinTimeout = flag.Duration("jointimeout", defaults.JoinTimeout, "Time allowed to join the ring before exiting.")
+
+	// JoinBackoff is the initial wait between rounds of join attempts across all seeds.
+	JoinBackoff = flag.Duration("joinbackoff", defaults.JoinBackoff, "Initial backoff between join attempts, doubled every round.")
+
+	// JoinMaxBackoff caps the wait between rounds of join attempts.
+	JoinMaxBackoff = flag.Duration("joinmaxbackoff", defaults.JoinMaxBackoff, "Maximum backoff between join attempts.")
+
 	// ApiPort sets the HTTP API port.
 	ApiPort = flag.String("httpport", DefaultApiPort, "REST API Port.")
+
+	// ShutdownTimeout bounds draining the HTTP API on shutdown.
+	ShutdownTimeout = flag.Duration("shutdowntimeout", defaults.ShutdownTimeout, "Time allowed for in-flight HTTP requests to drain on shutdown.")
+
+	// DataDir persists the keys of the VNodes.
+	DataDir = flag.String("datadir", defaults.DataDir, "Directory persisting the keys of the VNodes, one subdirectory per VNode ID. Keys are held in memory if empty.")
+
+	// CompactInterval is the period between compactions of the write-ahead logs.
+	CompactInterval = flag.Duration("compact", defaults.CompactInterval, "Interval between snapshots compacting the write-ahead logs in -datadir, 0 compacts only by size.")
+
+	// PersistInterval is the period between saves of the routing state.
+	PersistInterval = flag.Duration("persist", defaults.PersistInterval, "Interval between saves of the routing state of the VNodes to -datadir, restarted nodes rejoin through the saved peers.")
+
+	// Replicas is the number of VNodes storing every key.
+	Replicas = flag.Int("replicas", defaults.Replicas, "Number of VNodes storing every key, the owner and its successors. At most -successors + 1.")
+
+	// ReadConsistency and WriteConsistency are the default consistencies of the key-value store.
+	ReadConsistency  = flag.String("readconsistency", defaults.ReadConsistency, "Replicas reads wait for unless chosen per request: 'one', 'quorum' or 'all'.")
+	WriteConsistency = flag.String("writeconsistency", defaults.WriteConsistency, "Replicas writes wait for unless chosen per request: 'one', 'quorum' or 'all'.")
+
+	// HTTPAuth is the credentials file of the HTTP API.
+	HTTPAuth = flag.String("httpauth", defaults.HTTPAuth, "Credentials file of the HTTP API, lines of '<role> token <token>' or '<role> cert <common name>'. Authentication is disabled if empty.")
+
+	// HTTPTLS serves the HTTP API over TLS.
+	HTTPTLS = flag.Bool("httptls", defaults.HTTPTLS, "Serve the HTTP API over TLS with -tlscert, clients may authenticate with certificates signed by -tlsca.")
+
+	// LogLevel sets the default log level of all components.
+	LogLevel = flag.String("loglevel", defaults.LogLevel, "Default log level: debug, info, warn or error.")
+
+	// LogFormat selects the encoding of log entries.
+	LogFormat = flag.String("logformat", defaults.LogFormat, "Log format: 'logfmt' or 'json'.")
+
+	// LogComponents overrides the log level of individual components.
+	LogComponents = flag.String("loglevels", defaults.LogLevels, "Per component log levels, e.g. 'vnode=debug,rpc=warn'. Components: main, ring, vnode, rpc, http.")
+
+	// MinStabilizeInterval is the lower bound of the adaptive stabilization interval.
+	MinStabilizeInterval = flag.Duration("minstabilize", defaults.MinStabilizeInterval, "Stabilization interval while the ring is changing, e.g. 500ms.")
+
+	// MaxStabilizeInterval is the upper bound of the adaptive stabilization interval.
+	MaxStabilizeInterval = flag.Duration("maxstabilize", defaults.MaxStabilizeInterval, "Stabilization interval backed off to while the ring is quiet.")
+
+	// FixFingerInterval is the lower bound of the adaptive finger fixing interval.
+	FixFingerInterval = flag.Duration("fixfinger", defaults.FixFingerInterval, "Finger fixing interval while the ring is changing.")
+
+	// MaxFixFingerInterval is the upper bound of the adaptive finger fixing interval.
+	MaxFixFingerInterval = flag.Duration("maxfixfinger", defaults.MaxFixFingerInterval, "Finger fixing interval backed off to while the ring is quiet.")
+
+	// CheckPredInterval is the period between predecessor liveness checks.
+	CheckPredInterval = flag.Duration("checkpred", defaults.CheckPredInterval, "Predecessor liveness check interval.")
+
+	// PhiThreshold is the failure detector threshold.
+	PhiThreshold = flag.Float64("phi", defaults.PhiThreshold, "Suspicion threshold of the failure detector, unresponsive VNodes are declared dead above it.")
+
+	// VerifyInterval is the period between invariant checks.
+	VerifyInterval = flag.Duration("verify", defaults.VerifyInterval, "Invariant check interval, 0 disables the checks.")
+
+	// MaxSuccessors is the size of the successor table.
+	MaxSuccessors = flag.Int("successors", defaults.MaxSuccessors, "No of successors in the successor table.")
+
+	// MaxFingers is the size of the finger table.
+	MaxFingers = flag.Int("fingers", defaults.MaxFingers, "No of fingers in the finger table.")
 )
+
+// config is the effective configuration of the node.
+var config *Config.Config
+
+// InitConfig builds the effective configuration from the defaults, the config file
+// and the flags set on the command line, in increasing order of precedence.
+func InitConfig() error {
+	cfg := Config.Default()
+	if *ConfigFile != "" {
+		if err := cfg.LoadFile(*ConfigFile); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.SetFlags(flag.CommandLine); err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	config = cfg
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Role is a permission level of the HTTP API, every role includes the roles below it.
+type Role int
+
+const (
+	// NoRole is required by endpoints open to everyone, like readiness probes.
+	NoRole Role = iota
+	// ReadRole permits lookups, reading keys and inspecting the ring.
+	ReadRole
+	// WriteRole permits writing and deleting keys and publishing.
+	WriteRole
+	// AdminRole permits changing log levels and making the node leave.
+	AdminRole
+)
+
+var roleNames = map[Role]string{
+	NoRole:    "none",
+	ReadRole:  "read",
+	WriteRole: "write",
+	AdminRole: "admin",
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// ParseRole parses "read", "write" or "admin".
+func ParseRole(name string) (Role, error) {
+	for role, roleName := range roleNames {
+		if role != NoRole && roleName == name {
+			return role, nil
+		}
+	}
+	return NoRole, fmt.Errorf("unknown role %q, use read, write or admin", name)
+}
+
+// requiredRole returns the role permitted to make req.
+func requiredRole(req *http.Request) Role {
+	path := req.URL.Path
+	switch {
+	case path == "/ready":
+		return NoRole
+	case path == "/leave":
+		return AdminRole
+	case path == "/loglevel" && req.Method != "GET":
+		return AdminRole
+	case path == "/publish":
+		return WriteRole
+	case strings.HasPrefix(path, "/kv/") && req.Method != "GET":
+		return WriteRole
+	}
+	return ReadRole
+}
+
+// Credentials maps bearer tokens and the common names of client certificates to roles.
+type Credentials struct {
+	// tokens maps the SHA-256 of tokens to roles, so lookups do not leak tokens through timing.
+	tokens map[[sha256.Size]byte]Role
+	certs  map[string]Role
+}
+
+// LoadCredentials reads a credentials file. Every line holds a role, a kind
+// and a credential: "<role> token <token>" or "<role> cert <common name>".
+// Blank lines and lines starting with # are ignored.
+func LoadCredentials(path string) (*Credentials, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	credentials := &Credentials{
+		tokens: make(map[[sha256.Size]byte]Role),
+		certs:  make(map[string]Role),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("%s:%d: expected <role> <token|cert> <credential>", path, n)
+		}
+		role, err := ParseRole(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		credential := strings.Join(parts[2:], " ")
+
+		switch parts[1] {
+		case "token":
+			credentials.tokens[sha256.Sum256([]byte(credential))] = role
+		case "cert":
+			credentials.certs[credential] = role
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown kind %q, use token or cert", path, n, parts[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// authenticate returns the principal making req and its role. Requests with a
+// bearer token are judged by the token alone, others by their verified client certificate.
+func (c *Credentials) authenticate(req *http.Request) (string, Role, bool) {
+	if header := req.Header.Get("Authorization"); header != "" {
+		if !strings.HasPrefix(header, "Bearer ") {
+			return "", NoRole, false
+		}
+		role, ok := c.tokens[sha256.Sum256([]byte(strings.TrimPrefix(header, "Bearer ")))]
+		return "token", role, ok
+	}
+
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 {
+		name := req.TLS.VerifiedChains[0][0].Subject.CommonName
+		role, ok := c.certs[name]
+		return "cert:" + name, role, ok
+	}
+
+	return "", NoRole, false
+}
+
+// credentials authenticate HTTP API requests, nil if authentication is disabled.
+var credentials *Credentials
+
+// InitAuth loads the credentials of the HTTP API if a credentials file is configured.
+// Certificate credentials require -tlsca, client certificates chaining to the
+// system roots would otherwise be trusted to name their principal.
+func InitAuth() error {
+	if config.HTTPAuth == "" {
+		return nil
+	}
+
+	loaded, err := LoadCredentials(config.HTTPAuth)
+	if err != nil {
+		return err
+	}
+	if len(loaded.certs) > 0 && config.TLSCA == "" {
+		return fmt.Errorf("%s: cert credentials require -tlsca, the CA client certificates are verified against", config.HTTPAuth)
+	}
+
+	credentials = loaded
+	return nil
+}
+
+// authorize wraps handler with authentication and role based authorization.
+// Unauthenticated requests get 401 Unauthorized, requests beyond the role
+// of their principal 403 Forbidden.
+func authorize(credentials *Credentials, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		required := requiredRole(req)
+		if required == NoRole {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		principal, role, ok := credentials.authenticate(req)
+		if !ok {
+			httpLogger.Warn("Unauthenticated request", "remote", req.RemoteAddr, "method", req.Method, "path", req.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chord"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		if role < required {
+			httpLogger.Warn("Forbidden request", "remote", req.RemoteAddr, "principal", principal, "role", role, "required", required, "method", req.Method, "path", req.URL.Path)
+			http.Error(w, fmt.Sprintf("%s role required", required), http.StatusForbidden)
+			return
+		}
+
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// httpTLS returns the TLS configuration of the HTTP API, nil if it is served over plain HTTP.
+// Clients may present certificates signed by -tlsca to authenticate, client
+// certificates are not requested without it.
+func httpTLS() *tls.Config {
+	if !config.HTTPTLS {
+		return nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: rpcTLS.Certificates,
+	}
+	if config.TLSCA != "" {
+		tlsConfig.ClientCAs = rpcTLS.ClientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig
+}
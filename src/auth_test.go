@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	Config "github.com/arush15june/chord-golang/src/pkg/config"
+)
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   Role
+	}{
+		{"GET", "/ready", NoRole},
+		{"POST", "/lookup", ReadRole},
+		{"GET", "/kv/a", ReadRole},
+		{"PUT", "/kv/a", WriteRole},
+		{"DELETE", "/kv/a", WriteRole},
+		{"POST", "/publish", WriteRole},
+		{"GET", "/subscribe", ReadRole},
+		{"GET", "/loglevel", ReadRole},
+		{"POST", "/loglevel", AdminRole},
+		{"POST", "/leave", AdminRole},
+		{"GET", "/ring", ReadRole},
+	}
+	for _, test := range tests {
+		if got := requiredRole(httptest.NewRequest(test.method, test.path, nil)); got != test.want {
+			t.Errorf("requiredRole(%s %s) = %v, want %v", test.method, test.path, got, test.want)
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	for _, role := range []Role{ReadRole, WriteRole, AdminRole} {
+		if got, err := ParseRole(role.String()); err != nil || got != role {
+			t.Errorf("ParseRole(%q) = %v, %v, want %v", role.String(), got, err, role)
+		}
+	}
+	for _, name := range []string{"none", "", "Admin"} {
+		if _, err := ParseRole(name); err == nil {
+			t.Errorf("ParseRole(%q) succeeded, want an error", name)
+		}
+	}
+}
+
+// writeCredentials writes a credentials file with content to dir.
+func writeCredentials(t *testing.T, dir string, content string) string {
+	path := filepath.Join(dir, "credentials")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := LoadCredentials(writeCredentials(t, dir, `
+# role  kind   credential
+read    token  reader
+admin   token  two words
+write   cert   ingest
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		header        string
+		cert          string
+		wantPrincipal string
+		wantRole      Role
+		wantOK        bool
+	}{
+		{"Bearer reader", "", "token", ReadRole, true},
+		{"Bearer two words", "", "token", AdminRole, true},
+		{"Bearer unknown", "", "token", NoRole, false},
+		{"Basic reader", "", "", NoRole, false},
+		{"bearer reader", "", "", NoRole, false},
+		{"", "ingest", "cert:ingest", WriteRole, true},
+		{"", "other", "cert:other", NoRole, false},
+		{"Bearer unknown", "ingest", "token", NoRole, false},
+		{"", "", "", NoRole, false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/ring", nil)
+		if test.header != "" {
+			req.Header.Set("Authorization", test.header)
+		}
+		if test.cert != "" {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: test.cert}}
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+		principal, role, ok := c.authenticate(req)
+		if principal != test.wantPrincipal || role != test.wantRole || ok != test.wantOK {
+			t.Errorf("authenticate(%q, cert %q) = %q, %v, %v, want %q, %v, %v",
+				test.header, test.cert, principal, role, ok, test.wantPrincipal, test.wantRole, test.wantOK)
+		}
+	}
+}
+
+func TestLoadCredentialsErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"read token", ":1: expected"},
+		{"\nsuper token a", ":2: unknown role"},
+		{"read password a", ":1: unknown kind"},
+	}
+	for _, test := range tests {
+		_, err := LoadCredentials(writeCredentials(t, dir, test.content))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("LoadCredentials(%q) error = %v, want %q", test.content, err, test.want)
+		}
+	}
+	if _, err := LoadCredentials(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadCredentials() of a missing file succeeded")
+	}
+}
+
+func TestCertCredentialsRequireCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(c *Config.Config, tlsConfig *tls.Config) { config, rpcTLS, credentials = c, tlsConfig, nil }(config, rpcTLS)
+
+	config = Config.Default()
+	config.HTTPTLS = true
+	config.HTTPAuth = writeCredentials(t, dir, "write cert ingest\n")
+	rpcTLS = &tls.Config{ClientCAs: x509.NewCertPool()}
+
+	if err := InitAuth(); err == nil {
+		t.Error("InitAuth() accepted cert credentials without -tlsca")
+	}
+	if tlsConfig := httpTLS(); tlsConfig.ClientAuth != tls.NoClientCert || tlsConfig.ClientCAs != nil {
+		t.Error("httpTLS() requests client certificates without -tlsca")
+	}
+
+	config.TLSCA = filepath.Join(dir, "ca.pem")
+	if err := InitAuth(); err != nil {
+		t.Errorf("InitAuth() = %v with -tlsca", err)
+	}
+	if tlsConfig := httpTLS(); tlsConfig.ClientAuth != tls.VerifyClientCertIfGiven || tlsConfig.ClientCAs != rpcTLS.ClientCAs {
+		t.Error("httpTLS() does not verify client certificates against -tlsca")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+)
+
+// Client calls the HTTP API of a node.
+type Client struct {
+	base  string
+	http  *http.Client
+	token string
+}
+
+// NewClient creates a Client for the HTTP API at addr, authenticating with token if set.
+// https:// addresses are verified against -tlsca and presented -tlscert.
+func NewClient(addr string, timeout time.Duration, token string) (*Client, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+
+	httpClient := &http.Client{Timeout: timeout}
+	if strings.HasPrefix(addr, "https://") {
+		tlsConfig, err := Chord.LoadTLSConfig(*TLSCert, *TLSKey, *TLSCA, false)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	return &Client{
+		base:  strings.TrimSuffix(addr, "/"),
+		http:  httpClient,
+		token: token,
+	}, nil
+}
+
+// StatusError is returned for responses with an unexpected status.
+type StatusError struct {
+	Status  int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+}
+
+// Do sends a request to path and returns the body of a 2xx response.
+func (c *Client) Do(method string, path string, contentType string, body io.Reader) ([]byte, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	resp, data, err := c.Send(method, path, header, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{Status: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+
+	return data, nil
+}
+
+// Send sends a request with header to path and returns the response and its body, whatever its status.
+func (c *Client) Send(method string, path string, header http.Header, body io.Reader) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, data, nil
+}
+
+// Get sends a GET request to path.
+func (c *Client) Get(path string) ([]byte, error) {
+	return c.Do("GET", path, "", nil)
+}
+
+// GetJSON sends a GET request to path and decodes the JSON response into v.
+func (c *Client) GetJSON(path string, v interface{}) error {
+	data, err := c.Get(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// PostForm sends a form encoded POST request to path.
+func (c *Client) PostForm(path string, form url.Values) ([]byte, error) {
+	return c.Do("POST", path, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+	Topology "github.com/arush15june/chord-golang/src/pkg/topology"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// stdout is where commands print their output.
+var stdout io.Writer = os.Stdout
+
+// printJSON prints v as indented JSON.
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printTable prints rows as aligned columns under header.
+func printTable(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// output prints v in JSON mode and the table otherwise.
+func output(v interface{}, header []string, rows [][]string) error {
+	if *Output == "json" {
+		return printJSON(v)
+	}
+	return printTable(header, rows)
+}
+
+// kvPath returns the HTTP API path of key, with the consistency if set.
+func kvPath(key string, param string, consistency string) string {
+	path := "/kv/" + url.PathEscape(key)
+	if consistency != "" {
+		path += "?" + url.Values{param: {consistency}}.Encode()
+	}
+	return path
+}
+
+// Lookup prints the VNode owning a key.
+func Lookup(client *Client, args []string) error {
+	data, err := client.PostForm("/lookup", url.Values{"key": {args[0]}})
+	if err != nil {
+		return err
+	}
+
+	owner := strings.TrimSpace(string(data))
+	return output(map[string]string{"key": args[0], "owner": owner},
+		[]string{"KEY", "OWNER"}, [][]string{{args[0], owner}})
+}
+
+// contextHeader is the header carrying the causal context of a key.
+const contextHeader = "X-Chord-Context"
+
+// kvDo sends a request on key with the -context flag, failing unless the response is 2xx.
+func kvDo(client *Client, method string, key string, body io.Reader) error {
+	header := http.Header{}
+	if body != nil {
+		header.Set("Content-Type", "application/octet-stream")
+	}
+	if *Context != "" {
+		header.Set(contextHeader, *Context)
+	}
+
+	resp, data, err := client.Send(method, kvPath(key, "w", *Write), header, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{Status: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	return nil
+}
+
+// Put stores a value under a key.
+func Put(client *Client, args []string) error {
+	value := []byte(args[1])
+	if args[1] == "-" {
+		var err error
+		if value, err = ioutil.ReadAll(os.Stdin); err != nil {
+			return err
+		}
+	}
+
+	if err := kvDo(client, "PUT", args[0], bytes.NewReader(value)); err != nil {
+		return err
+	}
+
+	return output(map[string]interface{}{"key": args[0], "stored": len(value)},
+		[]string{"KEY", "STORED"}, [][]string{{args[0], fmt.Sprintf("%d bytes", len(value))}})
+}
+
+// siblings is the response of the node for a key with conflicting values.
+type siblings struct {
+	Key     string   `json:"key"`
+	Values  [][]byte `json:"values"`
+	Context string   `json:"context"`
+}
+
+// Get prints the value of a key, raw in table mode. Conflicting values are
+// printed with their context, which put resolves them with.
+func Get(client *Client, args []string) error {
+	resp, data, err := client.Send("GET", kvPath(args[0], "r", *Read), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusMultipleChoices:
+		conflict := &siblings{}
+		if err := json.Unmarshal(data, conflict); err != nil {
+			return err
+		}
+		values := make([]string, len(conflict.Values))
+		rows := make([][]string, len(conflict.Values))
+		for i, value := range conflict.Values {
+			values[i] = string(value)
+			rows[i] = []string{fmt.Sprint(i + 1), values[i]}
+		}
+		if *Output == "json" {
+			return printJSON(map[string]interface{}{"key": args[0], "values": values, "context": conflict.Context})
+		}
+		if err := printTable([]string{"SIBLING", "VALUE"}, rows); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "\ncontext: %s\n", conflict.Context)
+		return nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return &StatusError{Status: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+
+	if *Output == "json" {
+		return printJSON(map[string]string{"key": args[0], "value": string(data), "context": resp.Header.Get(contextHeader)})
+	}
+	_, err = stdout.Write(data)
+	if err == nil && !bytes.HasSuffix(data, []byte("\n")) {
+		fmt.Fprintln(stdout)
+	}
+	return err
+}
+
+// Delete deletes a key.
+func Delete(client *Client, args []string) error {
+	if err := kvDo(client, "DELETE", args[0], nil); err != nil {
+		return err
+	}
+
+	return output(map[string]interface{}{"key": args[0], "deleted": true},
+		[]string{"KEY", "DELETED"}, [][]string{{args[0], "yes"}})
+}
+
+// Ring walks the ring and prints every VNode and the inconsistencies found.
+func Ring(client *Client, args []string) error {
+	topology := &Topology.Topology{}
+	if err := client.GetJSON("/ring?format=json", topology); err != nil {
+		return err
+	}
+	if *Output == "json" {
+		return printJSON(topology)
+	}
+
+	rows := make([][]string, 0, len(topology.VNodes))
+	for _, info := range topology.VNodes {
+		successor := ""
+		if len(info.Successors) > 0 {
+			successor = info.Successors[0]
+		}
+		state := "ok"
+		if topology.HasIssues(info.Hostname) {
+			state = "ISSUES"
+		}
+		rows = append(rows, []string{fmt.Sprint(info.ID), info.Hostname, info.Predecessor, successor, state})
+	}
+	if err := printTable([]string{"ID", "HOSTNAME", "PREDECESSOR", "SUCCESSOR", "STATE"}, rows); err != nil {
+		return err
+	}
+
+	state := "complete"
+	if !topology.Complete {
+		state = "incomplete"
+	}
+	fmt.Fprintf(stdout, "\n%d VNodes, walk %s, %d issues\n", len(topology.VNodes), state, len(topology.Issues))
+	for _, issue := range topology.Issues {
+		fmt.Fprintf(stdout, "  %s at %s: %s\n", issue.Kind, issue.VNode, issue.Detail)
+	}
+	return nil
+}
+
+// rpcTransport returns the transport reaching VNodes over RPC, secured if TLS flags are set.
+func rpcTransport() (*Chord.Transport, error) {
+	if *TLSCA == "" && *TLSCert == "" {
+		return Chord.NewTransport(Chord.WithDialTimeout(*Timeout), Chord.WithCallTimeout(*Timeout)), nil
+	}
+
+	tlsConfig, err := Chord.LoadTLSConfig(*TLSCert, *TLSKey, *TLSCA, false)
+	if err != nil {
+		return nil, err
+	}
+	return Chord.NewTransport(Chord.WithDialTimeout(*Timeout), Chord.WithCallTimeout(*Timeout), Chord.WithTLS(tlsConfig)), nil
+}
+
+// Fingers prints the finger table of a VNode. The VNode given as argument
+// is asked over RPC, otherwise the first VNode of the node over HTTP.
+func Fingers(client *Client, args []string) error {
+	var info *VNode.Info
+	if len(args) == 1 {
+		transport, err := rpcTransport()
+		if err != nil {
+			return err
+		}
+		info, err = transport.Remote(args[0]).Info()
+		if err != nil {
+			return err
+		}
+	} else {
+		topology := &Topology.Topology{}
+		if err := client.GetJSON("/ring?format=json", topology); err != nil {
+			return err
+		}
+		if len(topology.VNodes) == 0 {
+			return fmt.Errorf("no VNode found")
+		}
+		info = &topology.VNodes[0]
+	}
+
+	if *Output == "json" {
+		return printJSON(info)
+	}
+
+	rows := make([][]string, 0, len(info.Fingers))
+	for i, finger := range info.Fingers {
+		start := info.ID + uint64(math.Exp2(float64(i)))
+		rows = append(rows, []string{fmt.Sprint(i + 1), fmt.Sprint(start), finger})
+	}
+	fmt.Fprintf(stdout, "VNode %s (%d)\n\n", info.Hostname, info.ID)
+	return printTable([]string{"FINGER", "START", "HOSTNAME"}, rows)
+}
+
+// Leave makes the node leave the ring and shut down.
+func Leave(client *Client, args []string) error {
+	if _, err := client.Do("POST", "/leave", "", nil); err != nil {
+		return err
+	}
+
+	return output(map[string]string{"node": *Addr, "state": "leaving"},
+		[]string{"NODE", "STATE"}, [][]string{{*Addr, "leaving"}})
+}
+
+// Health prints the readiness and size estimate of the node.
+// It fails if the node is not ready.
+func Health(client *Client, args []string) error {
+	ready := true
+	if _, err := client.Get("/ready"); err != nil {
+		if statusErr, ok := err.(*StatusError); !ok || statusErr.Status != http.StatusServiceUnavailable {
+			return err
+		}
+		ready = false
+	}
+
+	size := map[string]float64{}
+	if err := client.GetJSON("/size", &size); err != nil {
+		return err
+	}
+
+	err := output(map[string]interface{}{"node": *Addr, "ready": ready, "estimate": size["estimate"]},
+		[]string{"NODE", "READY", "ESTIMATED VNODES"}, [][]string{{*Addr, fmt.Sprint(ready), fmt.Sprintf("%.1f", size["estimate"])}})
+	if err == nil && !ready {
+		err = fmt.Errorf("node is not ready")
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+package main
+
+// chordctl is the command line client of chord nodes.
+// It talks to the HTTP API of a node, and to the RPC servers of VNodes
+// for commands inspecting a single VNode.
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Exit codes of chordctl.
+const (
+	// exitOK is returned when the command succeeded.
+	exitOK = 0
+	// exitFailed is returned when the command failed or the node is unhealthy.
+	exitFailed = 1
+	// exitUsage is returned for invalid commands, flags or arguments.
+	exitUsage = 2
+)
+
+var (
+	// Addr is the address of the HTTP API of the node.
+	Addr = flag.String("addr", "127.0.0.1:8090", "HTTP API address of the node.")
+
+	// Output selects the output mode.
+	Output = flag.String("o", "table", "Output: 'table' or 'json'.")
+
+	// Timeout bounds every request to the node.
+	Timeout = flag.Duration("timeout", 10*time.Second, "Timeout of requests to the node.")
+
+	// TLSCA, TLSCert and TLSKey secure RPC connections to VNodes and HTTPS connections to the node.
+	TLSCA   = flag.String("tlsca", "", "PEM CA certificates VNodes and https:// nodes are verified against, enables TLS for RPC.")
+	TLSCert = flag.String("tlscert", "", "PEM client certificate for VNodes requiring mutual TLS and for authenticating to the HTTP API.")
+	TLSKey  = flag.String("tlskey", "", "PEM key of -tlscert.")
+
+	// Context is the causal context of put and delete.
+	Context = flag.String("context", "", "Causal context of put and delete, as printed by 'get -o json' or for conflicting values. Without it all values of the key are replaced.")
+
+	// Read and Write are the consistencies of get, and of put and delete.
+	Read  = flag.String("r", "", "Replicas get waits for: 'one', 'quorum' or 'all'. The node's default if empty.")
+	Write = flag.String("w", "", "Replicas put and delete wait for: 'one', 'quorum' or 'all'. The node's default if empty.")
+
+	// Token authenticates to the HTTP API.
+	Token = flag.String("token", os.Getenv("CHORD_TOKEN"), "Bearer token for the HTTP API, defaults to $CHORD_TOKEN.")
+)
+
+// command is a chordctl subcommand.
+type command struct {
+	usage       string
+	description string
+	// args is the number of required arguments, optional is the number of optional ones.
+	args     int
+	optional int
+	run      func(client *Client, args []string) error
+}
+
+// accepts reports whether cmd takes the number of arguments in args.
+func (cmd command) accepts(args []string) bool {
+	return len(args) >= cmd.args && len(args) <= cmd.args+cmd.optional
+}
+
+var commands = map[string]command{
+	"lookup":  {"lookup <key>", "Print the VNode owning key.", 1, 0, Lookup},
+	"put":     {"put <key> <value>", "Store value under key, '-' reads the value from stdin.", 2, 0, Put},
+	"get":     {"get <key>", "Print the value of key, or all of its conflicting values and their context.", 1, 0, Get},
+	"delete":  {"delete <key>", "Delete key.", 1, 0, Delete},
+	"ring":    {"ring", "Walk the ring and print every VNode and inconsistencies.", 0, 0, Ring},
+	"fingers": {"fingers [vnode]", "Print the finger table of a VNode, by default the first VNode of the node.", 0, 1, Fingers},
+	"leave":   {"leave", "Make the node leave the ring and shut down.", 0, 0, Leave},
+	"health":  {"health", "Print readiness and size estimate, fails if the node is not ready.", 0, 0, Health},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: chordctl [flags] <command> [args]\n\nCommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", commands[name].usage, commands[name].description)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	if *Output != "table" && *Output != "json" {
+		fmt.Fprintf(os.Stderr, "chordctl: unknown output %q, use table or json\n", *Output)
+		os.Exit(exitUsage)
+	}
+
+	name, args := flag.Arg(0), flag.Args()[1:]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "chordctl: unknown command %q\n\n", name)
+		usage()
+		os.Exit(exitUsage)
+	}
+	if !cmd.accepts(args) {
+		fmt.Fprintf(os.Stderr, "Usage: chordctl %s\n", cmd.usage)
+		os.Exit(exitUsage)
+	}
+
+	client, err := NewClient(*Addr, *Timeout, *Token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chordctl: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if err := cmd.run(client, args); err != nil {
+		fmt.Fprintf(os.Stderr, "chordctl: %s: %v\n", name, err)
+		os.Exit(exitFailed)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandArguments(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		want    bool
+	}{
+		{"lookup", []string{"key"}, true},
+		{"lookup", nil, false},
+		{"lookup", []string{"key", "extra"}, false},
+		{"put", []string{"key", "value"}, true},
+		{"put", []string{"key"}, false},
+		{"get", []string{"key"}, true},
+		{"delete", nil, false},
+		{"ring", nil, true},
+		{"ring", []string{"extra"}, false},
+		{"fingers", nil, true},
+		{"fingers", []string{"127.0.0.1:8000"}, true},
+		{"fingers", []string{"127.0.0.1:8000", "extra"}, false},
+		{"leave", nil, true},
+		{"health", []string{"extra"}, false},
+	}
+	for _, test := range tests {
+		if got := commands[test.command].accepts(test.args); got != test.want {
+			t.Errorf("%s %q: accepts() = %v, want %v", test.command, test.args, got, test.want)
+		}
+	}
+}
+
+// node serves a fake HTTP API of a node with two VNodes and one issue.
+func node(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lookup", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.FormValue("key") != "apple" {
+			http.Error(w, "unexpected lookup", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "127.0.0.1:8001")
+	})
+	mux.HandleFunc("/ring", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"start":"127.0.0.1:8000","complete":true,"vnodes":[`+
+			`{"id":10,"hostname":"127.0.0.1:8000","predecessor":"127.0.0.1:8001","successors":["127.0.0.1:8001"],"fingers":["127.0.0.1:8001",""]},`+
+			`{"id":20,"hostname":"127.0.0.1:8001","predecessor":"127.0.0.1:8002","successors":["127.0.0.1:8000"]}],`+
+			`"issues":[{"kind":"predecessor-mismatch","vnode":"127.0.0.1:8001","detail":"predecessor is 127.0.0.1:8002"}]}`)
+	})
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/size", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"estimate":2}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+// run runs the command against server in the output mode and returns what it printed.
+func run(t *testing.T, server *httptest.Server, mode string, name string, args ...string) (string, error) {
+	t.Helper()
+	defer func(addr, output string) { *Addr, *Output = addr, output }(*Addr, *Output)
+	*Addr, *Output = server.URL, mode
+
+	var buf bytes.Buffer
+	defer func(w io.Writer) { stdout = w }(stdout)
+	stdout = &buf
+
+	client, err := NewClient(server.URL, time.Second, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = commands[name].run(client, args)
+	return buf.String(), err
+}
+
+func TestTableOutput(t *testing.T) {
+	server := node(t)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{"lookup", []string{"apple"}, []string{
+			"KEY    OWNER",
+			"apple  127.0.0.1:8001",
+		}, false},
+		{"ring", nil, []string{
+			"ID  HOSTNAME        PREDECESSOR     SUCCESSOR       STATE",
+			"10  127.0.0.1:8000  127.0.0.1:8001  127.0.0.1:8001  ok",
+			"20  127.0.0.1:8001  127.0.0.1:8002  127.0.0.1:8000  ISSUES",
+			"",
+			"2 VNodes, walk complete, 1 issues",
+			"  predecessor-mismatch at 127.0.0.1:8001: predecessor is 127.0.0.1:8002",
+		}, false},
+		{"fingers", nil, []string{
+			"VNode 127.0.0.1:8000 (10)",
+			"",
+			"FINGER  START  HOSTNAME",
+			"1       11     127.0.0.1:8001",
+			"2       12     ",
+		}, false},
+		{"health", nil, []string{
+			fmt.Sprintf("%-*s  READY  ESTIMATED VNODES", len(server.URL), "NODE"),
+			server.URL + "  false  2.0",
+		}, true},
+	}
+	for _, test := range tests {
+		out, err := run(t, server, "table", test.name, test.args...)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: error = %v, want error %v", test.name, err, test.wantErr)
+		}
+		if want := strings.Join(test.want, "\n") + "\n"; out != want {
+			t.Errorf("%s: printed\n%s\nwant\n%s", test.name, out, want)
+		}
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	server := node(t)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		args []string
+		want map[string]interface{}
+	}{
+		{"lookup", []string{"apple"}, map[string]interface{}{"key": "apple", "owner": "127.0.0.1:8001"}},
+		{"health", nil, map[string]interface{}{"node": server.URL, "ready": false, "estimate": 2.0}},
+	}
+	for _, test := range tests {
+		out, _ := run(t, server, "json", test.name, test.args...)
+		got := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Errorf("%s: printed invalid JSON %q: %v", test.name, out, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: printed %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	out, err := run(t, server, "json", "ring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var topology struct {
+		Complete bool              `json:"complete"`
+		VNodes   []json.RawMessage `json:"vnodes"`
+		Issues   []json.RawMessage `json:"issues"`
+	}
+	if err := json.Unmarshal([]byte(out), &topology); err != nil {
+		t.Fatalf("ring printed invalid JSON %q: %v", out, err)
+	}
+	if !topology.Complete || len(topology.VNodes) != 2 || len(topology.Issues) != 1 {
+		t.Errorf("ring printed %s, want the complete walk of 2 VNodes with 1 issue", out)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	server := node(t)
+	defer server.Close()
+
+	_, err := run(t, server, "table", "lookup", "pear")
+	if statusErr, ok := err.(*StatusError); !ok || statusErr.Status != http.StatusBadRequest || statusErr.Message != "unexpected lookup" {
+		t.Errorf("lookup error = %v, want a 400 StatusError", err)
+	}
+}
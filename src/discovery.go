@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	Discovery "github.com/arush15june/chord-golang/src/pkg/discovery"
+)
+
+// seedFileTTL is the time after which a seed file not refreshed by its process is ignored.
+const seedFileTTL = 30 * time.Second
+
+// discovery is the configured discovery provider, nil if discovery is disabled.
+var discovery Discovery.Provider
+
+// InitDiscovery creates the discovery provider selected by the configuration.
+func InitDiscovery() error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "chord"
+	}
+	name := hostname + "-" + config.Host + "-" + config.HTTPPort
+
+	switch config.Discovery {
+	case "mdns":
+		discovery = Discovery.NewMDNS(name, config.DiscoveryTimeout)
+	case "file":
+		registry, err := Discovery.NewFileRegistry(config.DiscoveryDir, name, seedFileTTL)
+		if err != nil {
+			return err
+		}
+		discovery = registry
+	}
+
+	return nil
+}
+
+// DiscoveredSeeds returns the hostnames of ring members found by discovery.
+// It is evaluated on every round of join attempts so that members
+// appearing while joining are picked up.
+func DiscoveredSeeds() []string {
+	if discovery == nil {
+		return nil
+	}
+
+	discovered, err := discovery.Seeds()
+	if err != nil {
+		logger.Warn("Seed discovery failed", "discovery", config.Discovery, "err", err)
+	}
+	logger.Debug("Discovered seeds", "discovery", config.Discovery, "seeds", len(discovered))
+
+	return discovered
+}
+
+// RegisterWorkers advertises the hostnames of the local workers to other processes.
+func RegisterWorkers() {
+	if discovery == nil {
+		return
+	}
+
+	hostnames := ring.Hostnames()
+
+	if err := discovery.Register(hostnames); err != nil {
+		logger.Warn("Failed to advertise workers", "discovery", config.Discovery, "err", err)
+		return
+	}
+	logger.Info("Advertising workers", "discovery", config.Discovery, "workers", len(hostnames))
+}
+
+// DeregisterWorkers withdraws the advertised hostnames of the local workers.
+func DeregisterWorkers() {
+	if discovery == nil {
+		return
+	}
+
+	if err := discovery.Deregister(); err != nil {
+		logger.Warn("Failed to withdraw workers", "discovery", config.Discovery, "err", err)
+	}
+}
@@ -1,8 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+	KV "github.com/arush15june/chord-golang/src/pkg/kv"
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+	Topology "github.com/arush15june/chord-golang/src/pkg/topology"
 )
 
 // KeyLookupHandler in HTTP Handler for looking up keys in chord.
@@ -15,9 +25,18 @@ func KeyLookupHandler(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 		key := req.FormValue("key")
-		host, err := LookupKey(key)
+		host, err := ring.Lookup(key)
 		if err != nil {
-			fmt.Fprintf(w, "Lookup err: %v", err)
+			status := http.StatusBadGateway
+			if err == Chord.ErrNotReady {
+				status = http.StatusServiceUnavailable
+			}
+			if err == Chord.ErrOverloaded {
+				overloaded(w)
+				status = http.StatusServiceUnavailable
+			}
+			http.Error(w, fmt.Sprintf("Lookup err: %v", err), status)
+			return
 		}
 
 		fmt.Fprintf(w, "%s\n", host)
@@ -26,8 +45,469 @@ func KeyLookupHandler(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
-func InitHttpServer() {
-	logger.Println("Initialized HTTP Server at port 8090")
-	http.HandleFunc("/lookup", KeyLookupHandler)
-	go http.ListenAndServe(":"+*ApiPort, nil)
+// LogLevelHandler is the HTTP Handler for reading and changing log levels at runtime.
+// GET returns the level of every component, POST sets the level of
+// the component named by the "component" form value ("default" for all others).
+func LogLevelHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "GET":
+	case "POST":
+		if err := req.ParseForm(); err != nil {
+			http.Error(w, fmt.Sprintf("ParseForm() err: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		level, err := Logging.ParseLevel(req.FormValue("level"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		component := req.FormValue("component")
+		if component == "" || component == "default" {
+			logRegistry.SetDefaultLevel(level)
+		} else {
+			logRegistry.SetLevel(component, level)
+		}
+		httpLogger.Info("Changed log level", "target", component, "level", level)
+	default:
+		http.Error(w, "Sorry, only GET and POST methods are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logRegistry.Levels())
+}
+
+// ConfigHandler is the HTTP Handler serving the effective configuration.
+// It returns JSON by default and a loadable YAML document with ?format=yaml.
+func ConfigHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "Sorry, only GET methods are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if req.URL.Query().Get("format") == "yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+		fmt.Fprint(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config.Values())
+}
+
+// ReadinessHandler is the HTTP Handler reporting whether all workers have joined the ring.
+// It responds 200 once ready and 503 before, so it can back readiness probes.
+func ReadinessHandler(w http.ResponseWriter, req *http.Request) {
+	if !ring.Ready() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintln(w, "ready")
+}
+
+// SizeHandler is the HTTP Handler serving the estimated number of VNodes in the ring.
+// The estimate is local and does not contact other nodes.
+func SizeHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "Sorry, only GET methods are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]float64{"estimate": ring.EstimateSize()})
+}
+
+// StatsHandler is the HTTP Handler aggregating VNode statistics over the ring.
+// The "stat" query parameter selects statistics and may be repeated, all are returned by default.
+func StatsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "Sorry, only GET methods are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := ring.Aggregate(req.URL.Query()["stat"]...)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == Chord.ErrNotReady {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// InvariantsHandler is the HTTP Handler serving the invariant violations found by the local VNodes,
+// counted by invariant, along with the most recent ones.
+func InvariantsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "Sorry, only GET methods are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	counts, recent := ring.Violations()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Counts map[string]uint64 `json:"counts"`
+		Recent []Chord.Violation `json:"recent"`
+	}{counts, recent})
+}
+
+// RingHandler is the HTTP Handler walking the ring from the first local VNode.
+// It serves the topology as JSON by default, ?format=dot and ?format=svg export Graphviz and a ring diagram.
+func RingHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "Sorry, only GET methods are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType := Topology.ContentType(format)
+	if contentType == "" {
+		http.Error(w, fmt.Sprintf("unknown format %q, use json, dot or svg", format), http.StatusBadRequest)
+		return
+	}
+
+	topology, err := Topology.Walk(ring.Transport(), ring.Hostnames()[0], Topology.DefaultLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	topology.Write(w, format)
+}
+
+// maxValueSize is the largest value accepted by KVHandler.
+const maxValueSize = 1 << 20
+
+// ContextHeader carries the causal context of a key, read with GET and
+// passed to PUT and DELETE to replace the values read.
+const ContextHeader = "X-Chord-Context"
+
+// siblingsResponse is the response to GET on a key with conflicting values.
+type siblingsResponse struct {
+	Key     string   `json:"key"`
+	Values  [][]byte `json:"values"`
+	Context string   `json:"context"`
+}
+
+// consistencyParam parses the consistency in the query parameter name, the default if absent.
+func consistencyParam(req *http.Request, name string) (KV.Consistency, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return KV.DefaultConsistency, nil
+	}
+	return KV.ParseConsistency(value)
+}
+
+// KVHandler is the HTTP Handler for the key-value store at /kv/<key>.
+// GET returns the value, PUT sets it to the request body and DELETE removes the key.
+// GET on a key with concurrent values responds 300 Multiple Choices with the
+// siblings as JSON. PUT and DELETE with the context of a GET replace the
+// values read, without a context all values. The r and w query parameters
+// choose the consistency of reads and writes: one, quorum or all.
+func KVHandler(w http.ResponseWriter, req *http.Request) {
+	key := strings.TrimPrefix(req.URL.Path, "/kv/")
+	if key == "" {
+		http.Error(w, "key is required", http.StatusBadRequest)
+		return
+	}
+
+	read, err := consistencyParam(req, "r")
+	if err != nil {
+		http.Error(w, "r: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	write, err := consistencyParam(req, "w")
+	if err != nil {
+		http.Error(w, "w: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var context KV.VClock
+	if header := req.Header.Get(ContextHeader); header != "" {
+		var err error
+		if context, err = KV.DecodeContext(header); err != nil {
+			http.Error(w, "invalid "+ContextHeader+" header", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch req.Method {
+	case "GET":
+		var siblings *KV.Siblings
+		siblings, err = store.GetSiblings(key, read)
+		if err == nil {
+			w.Header().Set(ContextHeader, KV.EncodeContext(siblings.Context))
+			if len(siblings.Values) > 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusMultipleChoices)
+				json.NewEncoder(w).Encode(siblingsResponse{Key: key, Values: siblings.Values, Context: KV.EncodeContext(siblings.Context)})
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(siblings.Values[0])
+			return
+		}
+	case "PUT", "POST":
+		var value []byte
+		value, err = ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxValueSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if context != nil {
+			err = store.PutContext(key, value, context, write)
+		} else {
+			err = store.Put(key, value, write)
+		}
+	case "DELETE":
+		if context != nil {
+			err = store.DeleteContext(key, context, write)
+		} else {
+			err = store.Delete(key, write)
+		}
+	default:
+		http.Error(w, "Sorry, only GET, PUT and DELETE methods are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case KV.ErrNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case Chord.ErrNotReady, KV.ErrUnavailable:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case Chord.ErrOverloaded:
+		overloaded(w)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	}
+}
+
+// LeaveHandler is the HTTP Handler making the node leave the ring and shut down, like SIGTERM.
+func LeaveHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(w, "Sorry, only POST methods are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	select {
+	case leaveRequests <- struct{}{}:
+	default:
+	}
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "leaving")
+}
+
+// maxPublishSize is the largest message body accepted by PublishHandler.
+const maxPublishSize = 1 << 20
+
+// newlines normalizes the line breaks of event data, server-sent events end lines at CRLF, LF or CR.
+var newlines = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// topicParam returns the "topic" query parameter. Topics are written to event
+// streams, so line breaks are rejected. It responds with an error and returns
+// false if the topic is missing or invalid.
+func topicParam(w http.ResponseWriter, req *http.Request) (string, bool) {
+	topic := req.URL.Query().Get("topic")
+	if topic == "" {
+		http.Error(w, "topic is required", http.StatusBadRequest)
+		return "", false
+	}
+	if strings.ContainsAny(topic, "\r\n") {
+		http.Error(w, "topic must not contain line breaks", http.StatusBadRequest)
+		return "", false
+	}
+
+	return topic, true
+}
+
+// SubscribeHandler is the HTTP Handler streaming the events of the "topic" query
+// parameter as server-sent events until the client disconnects.
+func SubscribeHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "Sorry, only GET methods are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	topic, ok := topicParam(w, req)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, err := pubsub.Subscribe(topic)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	releaseSlot(req)
+
+	for {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "event: %s\n", event.Topic)
+			for _, line := range strings.Split(newlines.Replace(string(event.Data)), "\n") {
+				fmt.Fprintf(w, "data: %s\n", line)
+			}
+			fmt.Fprint(w, "\n")
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// PublishHandler is the HTTP Handler publishing the request body on the "topic" query parameter.
+func PublishHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(w, "Sorry, only POST methods are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	topic, ok := topicParam(w, req)
+	if !ok {
+		return
+	}
+
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxPublishSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := pubsub.Publish(topic, data); err != nil {
+		status := http.StatusInternalServerError
+		if err == Chord.ErrNotReady {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	fmt.Fprintln(w, "published")
+}
+
+// retryAfter is the number of seconds clients of an overloaded node are asked to wait.
+const retryAfter = "1"
+
+// overloaded asks the client to back off before retrying.
+func overloaded(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", retryAfter)
+}
+
+// releaseKey is the request context key of the function releasing the admission slot of the request.
+type releaseKey struct{}
+
+// releaseSlot releases the concurrent request slot held by req. Long-lived
+// requests such as event streams release it once set up, so that they do not
+// hold the maximum of concurrent requests for their whole lifetime.
+func releaseSlot(req *http.Request) {
+	if release, ok := req.Context().Value(releaseKey{}).(func()); ok {
+		release()
+	}
+}
+
+// admit wraps handler with admission control. Requests beyond the rate limits
+// or the maximum of concurrent requests are refused with 429 Too Many Requests.
+// Readiness probes are always admitted, an overloaded node is still alive.
+func admit(admission *Chord.Admission, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/ready" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		if !admission.Acquire() {
+			overloaded(w)
+			http.Error(w, "too many concurrent requests", http.StatusTooManyRequests)
+			return
+		}
+		var once sync.Once
+		release := func() { once.Do(admission.Release) }
+		defer release()
+
+		if !admission.Allow(req.RemoteAddr) {
+			overloaded(w)
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		handler.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), releaseKey{}, release)))
+	})
+}
+
+var httpLogger *Logging.Logger
+
+// InitHttpServer starts the HTTP API in the background.
+// Errors which stop the server, other than a shutdown, are sent on the returned channel.
+func InitHttpServer() (*http.Server, <-chan error) {
+	httpLogger = NewLogger("http")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lookup", KeyLookupHandler)
+	mux.HandleFunc("/loglevel", LogLevelHandler)
+	mux.HandleFunc("/config", ConfigHandler)
+	mux.HandleFunc("/ready", ReadinessHandler)
+	mux.HandleFunc("/kv/", KVHandler)
+	mux.HandleFunc("/leave", LeaveHandler)
+	mux.HandleFunc("/ring", RingHandler)
+	mux.HandleFunc("/size", SizeHandler)
+	mux.HandleFunc("/stats", StatsHandler)
+	mux.HandleFunc("/invariants", InvariantsHandler)
+	mux.HandleFunc("/subscribe", SubscribeHandler)
+	mux.HandleFunc("/publish", PublishHandler)
+
+	var handler http.Handler = mux
+	if credentials != nil {
+		handler = authorize(credentials, handler)
+	}
+
+	server := &http.Server{
+		Addr:      ":" + config.HTTPPort,
+		Handler:   admit(Chord.NewAdmission(admissionPolicy()), handler),
+		TLSConfig: httpTLS(),
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			errs <- err
+		}
+	}()
+
+	httpLogger.Info("Initialized HTTP Server", "port", config.HTTPPort, "tls", server.TLSConfig != nil, "auth", credentials != nil)
+	return server, errs
 }
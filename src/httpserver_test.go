@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+)
+
+func TestTopicRejectsLineBreaks(t *testing.T) {
+	tests := []struct {
+		handler http.HandlerFunc
+		method  string
+		query   string
+	}{
+		{SubscribeHandler, "GET", "topic=news%0Aevent:%20forged"},
+		{SubscribeHandler, "GET", "topic=news%0Ddata:%20forged"},
+		{SubscribeHandler, "GET", ""},
+		{PublishHandler, "POST", "topic=news%0D%0Aid:%201"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		test.handler(w, httptest.NewRequest(test.method, "/subscribe?"+test.query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s ?%s: status %d, want %d", test.method, test.query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAdmitSlots(t *testing.T) {
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, req *http.Request) {
+		entered <- struct{}{}
+		<-unblock
+	})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, req *http.Request) {
+		releaseSlot(req)
+		entered <- struct{}{}
+		<-unblock
+	})
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, req *http.Request) {})
+	mux.HandleFunc("/other", func(w http.ResponseWriter, req *http.Request) {})
+
+	server := httptest.NewServer(admit(Chord.NewAdmission(Chord.AdmissionPolicy{MaxConns: 1}), mux))
+	defer server.Close()
+	defer close(unblock)
+
+	get := func(path string) int {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	slow := make(chan int)
+	go func() { slow <- get("/slow") }()
+
+	// A request holds the only slot.
+	<-entered
+	if status := get("/other"); status != http.StatusTooManyRequests {
+		t.Errorf("request beyond the maximum: status %d, want %d", status, http.StatusTooManyRequests)
+	}
+	if status := get("/ready"); status != http.StatusOK {
+		t.Errorf("readiness probe beyond the maximum: status %d, want %d", status, http.StatusOK)
+	}
+	unblock <- struct{}{}
+	if status := <-slow; status != http.StatusOK {
+		t.Fatalf("slow request: status %d, want %d", status, http.StatusOK)
+	}
+
+	// A stream gives its slot back once set up.
+	go get("/stream")
+	<-entered
+	if status := get("/other"); status != http.StatusOK {
+		t.Errorf("request next to an open stream: status %d, want %d", status, http.StatusOK)
+	}
+}
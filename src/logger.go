@@ -1,24 +1,44 @@
 package main
 
 import (
-	"fmt"
-	"log"
 	"os"
-)
 
-var logger *log.Logger
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+)
 
-// NewLogger produces a new logger with required name
-func NewLogger(name string) *log.Logger {
-	logger := log.New(os.Stdout,
-		fmt.Sprintf("%s: ", name),
-		log.Ldate|log.Ltime|log.LUTC|log.Lshortfile,
-	)
+var (
+	logRegistry *Logging.Registry
+	logger      *Logging.Logger
+)
 
-	return logger
+// NewLogger produces a new logger for the named component.
+func NewLogger(component string) *Logging.Logger {
+	return logRegistry.Logger(component).With("hostname", config.Host)
 }
 
-// InitLogger initializes the global logger.
-func InitLogger() {
-	logger = NewLogger(*HostName)
+// InitLogger initializes the log registry from the logging configuration and the global logger.
+func InitLogger() error {
+	level, err := Logging.ParseLevel(config.LogLevel)
+	if err != nil {
+		return err
+	}
+
+	format, err := Logging.ParseFormat(config.LogFormat)
+	if err != nil {
+		return err
+	}
+
+	// Walk mode prints the topology on stdout.
+	out := os.Stdout
+	if config.Mode == "walk" {
+		out = os.Stderr
+	}
+
+	logRegistry = Logging.NewRegistry(out, format, level)
+	if err := logRegistry.SetLevels(config.LogLevels); err != nil {
+		return err
+	}
+
+	logger = NewLogger("main")
+	return nil
 }
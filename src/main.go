@@ -1,107 +1,290 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+	Config "github.com/arush15june/chord-golang/src/pkg/config"
+	KV "github.com/arush15june/chord-golang/src/pkg/kv"
+	Scribe "github.com/arush15june/chord-golang/src/pkg/scribe"
+	Storage "github.com/arush15june/chord-golang/src/pkg/storage"
+	Topology "github.com/arush15june/chord-golang/src/pkg/topology"
+)
+
+// ring runs the VNode workers of the process.
+var ring *Chord.Ring
+
+// pubsub serves topic based publish/subscribe on the ring.
+var pubsub *Scribe.Scribe
+
+// store holds the keys owned by the workers.
+var store *KV.Store
+
+// leaveRequests receives requests to leave the ring through the HTTP API.
+var leaveRequests = make(chan struct{}, 1)
+
+// Exit codes of the process.
+const (
+	// exitOK is returned after a clean shutdown.
+	exitOK = 0
+	// exitShutdownError is returned when leaving the ring or draining the HTTP API failed.
+	exitShutdownError = 1
+	// exitConfigError is returned for invalid flags or config files.
+	exitConfigError = 2
+	// exitStartupError is returned when the ring could not be created or joined.
+	exitStartupError = 3
+	// exitServerError is returned when the HTTP API stopped unexpectedly.
+	exitServerError = 4
 )
 
 // Lookup is supported via an HTTP API.
 
+// logConfig logs the effective configuration of the node.
+func logConfig() {
+	keyvals := make([]interface{}, 0)
+	for _, key := range Config.Keys() {
+		value, _ := config.Get(key)
+		keyvals = append(keyvals, key, value)
+	}
+
+	logger.Info("Effective configuration", keyvals...)
+}
+
+// admissionPolicy returns the limits of the RPC servers and the HTTP API.
+func admissionPolicy() Chord.AdmissionPolicy {
+	return Chord.AdmissionPolicy{
+		PeerRate:   config.RateLimit,
+		GlobalRate: config.GlobalRateLimit,
+		Burst:      config.Burst,
+		MaxConns:   config.MaxConns,
+	}
+}
+
+// NewStore creates the key-value store, persisting keys under the data directory if one is configured.
+func NewStore() *KV.Store {
+	// The consistencies are validated with the configuration.
+	read, _ := KV.ParseConsistency(config.ReadConsistency)
+	write, _ := KV.ParseConsistency(config.WriteConsistency)
+	opts := []KV.Option{
+		KV.WithReplicas(config.Replicas),
+		KV.WithConsistency(read, write),
+	}
+	if config.DataDir != "" {
+		opts = append(opts, KV.WithStorage(Storage.DiskOpener(config.DataDir, config.CompactInterval, NewLogger("storage"))))
+	}
+	return KV.New(opts...)
+}
+
+// NewRing initializes the VNode workers of the ring from the configuration.
+func NewRing() (*Chord.Ring, error) {
+	opts := []Chord.Option{
+		Chord.WithHostname(config.Host),
+		Chord.WithVNodes(config.Workers),
+		Chord.WithStabilizeInterval(config.MinStabilizeInterval, config.MaxStabilizeInterval),
+		Chord.WithFixFingerInterval(config.FixFingerInterval),
+		Chord.WithMaxFixFingerInterval(config.MaxFixFingerInterval),
+		Chord.WithCheckPredecessorInterval(config.CheckPredInterval),
+		Chord.WithPhiThreshold(config.PhiThreshold),
+		Chord.WithVerifyInterval(config.VerifyInterval),
+		Chord.WithSuccessors(config.MaxSuccessors),
+		Chord.WithFingers(config.MaxFingers),
+		Chord.WithJoinPolicy(Chord.JoinPolicy{
+			Timeout:        config.JoinTimeout,
+			InitialBackoff: config.JoinBackoff,
+			MaxBackoff:     config.JoinMaxBackoff,
+		}),
+		Chord.WithSeedSource(DiscoveredSeeds),
+		Chord.WithDelegate(store),
+		Chord.WithStatsSource(store.Stats),
+		Chord.WithAdmissionPolicy(admissionPolicy()),
+		Chord.WithTLS(rpcTLS),
+		Chord.WithLogs(logRegistry),
+	}
+	if config.DataDir != "" {
+		opts = append(opts, Chord.WithStateDir(filepath.Join(config.DataDir, "ring"), config.PersistInterval))
+	}
+	if config.Identity {
+		opts = append(opts, Chord.WithIdentity(rpcTLS.Certificates[0], rpcTLS.RootCAs))
+	}
+
+	return Chord.New(opts...)
+}
+
 // CreateStrategy is used to create a new Chord ring.
-func CreateStrategy() {
-	nWorkers := *Workers
-	Hostname := *HostName
-
-	logger.Println("Creating New Ring")
-	logger.Printf("Hostname: %s", Hostname)
-	logger.Printf("VNode Workers: %d", nWorkers)
-
-	minStabilizeInterval := 15
-	maxStabilizeInterval := 45
-	fixFingerInterval := 15
-	checkPredInterval := 15
-	maxSuccessors := 1
-	maxFingers := 6
-
-	logger.Println("VNode Worker Configuration")
-	logger.Printf("Minimum Stabilization Interval: %d", minStabilizeInterval)
-	logger.Printf("Maximum Stabilization Interval: %d", maxStabilizeInterval)
-	logger.Printf("Finger Fixing Interval: %d", fixFingerInterval)
-	logger.Printf("Check Predecessor Interval: %d", checkPredInterval)
-	logger.Printf("No of Successors in Successor Table: %d", maxSuccessors)
-	logger.Printf("No of Fingers in Finger Table: %d", maxFingers)
-
-	CreateRing(
-		nWorkers,
-		Hostname,
-		minStabilizeInterval,
-		maxStabilizeInterval,
-		fixFingerInterval,
-		checkPredInterval,
-		maxSuccessors,
-		maxFingers,
-	)
+func CreateStrategy() error {
+	logger.Info("Creating New Ring", "workers", config.Workers)
 
+	return ring.Create()
 }
 
 // JoinStrategy is used to join an existing chord ring.
-func JoinStrategy() {
-	nWorkers := *Workers
-	Hostname := *HostName
-	RemoteHost := *RemoteHost
-
-	logger.Println("Joining Existing Ringg")
-	logger.Printf("Hostname: %s", Hostname)
-	logger.Printf("VNode Workers: %d", nWorkers)
-	logger.Printf("Remote Ring: %s", RemoteHost)
-
-	minStabilizeInterval := 15
-	maxStabilizeInterval := 45
-	fixFingerInterval := 15
-	checkPredInterval := 15
-	maxSuccessors := 1
-	maxFingers := 6
-
-	logger.Println("VNode Worker Configuration")
-	logger.Printf("Minimum Stabilization Interval: %d", minStabilizeInterval)
-	logger.Printf("Maximum Stabilization Interval: %d", maxStabilizeInterval)
-	logger.Printf("Finger Fixing Interval: %d", fixFingerInterval)
-	logger.Printf("Check Predecessor Interval: %d", checkPredInterval)
-	logger.Printf("No of Successors in Successor Table: %d", maxSuccessors)
-	logger.Printf("No of Fingers in Finger Table: %d", maxFingers)
-
-	JoinRing(
-		nWorkers,
-		Hostname,
-		RemoteHost,
-		minStabilizeInterval,
-		maxStabilizeInterval,
-		fixFingerInterval,
-		checkPredInterval,
-		maxSuccessors,
-		maxFingers,
-	)
+func JoinStrategy() error {
+	logger.Info("Joining Existing Ring", "workers", config.Workers, "seeds", config.RemoteHost, "discovery", config.Discovery)
+
+	return ring.Join(config.Seeds()...)
+}
+
+// WalkRing prints the topology of the ring reached through the first
+// responsive seed in the configured format and returns the exit status.
+func WalkRing() int {
+	if Topology.ContentType(*WalkFormat) == "" {
+		logger.Error("Unknown walk format", "format", *WalkFormat)
+		return exitConfigError
+	}
+
+	transport := Chord.NewTransport(Chord.WithLogs(logRegistry), Chord.WithTLS(rpcTLS))
+	for _, seed := range config.Seeds() {
+		logger.Info("Walking ring", "start", seed)
+		topology, err := Topology.Walk(transport, seed, Topology.DefaultLimit)
+		if err != nil {
+			logger.Warn("Failed to walk ring", "start", seed, "err", err)
+			continue
+		}
 
+		for _, issue := range topology.Issues {
+			logger.Warn("Ring inconsistency", "kind", issue.Kind, "vnode", issue.VNode, "detail", issue.Detail)
+		}
+		if err := topology.Write(os.Stdout, *WalkFormat); err != nil {
+			logger.Error("Failed to write topology", "err", err)
+			return exitShutdownError
+		}
+		return exitOK
+	}
+
+	return exitStartupError
+}
+
+// Shutdown leaves the ring with every worker, closes the RPC listeners and
+// drains the HTTP API. It returns exitShutdownError if any step failed.
+func Shutdown(server *http.Server) int {
+	status := exitOK
+
+	DeregisterWorkers()
+
+	// Close the subscriptions first, so streaming subscribers do not hold up draining the HTTP API.
+	pubsub.Close()
+
+	logger.Info("Leaving ring", "workers", config.Workers)
+	if err := ring.Leave(); err != nil {
+		logger.Error("Failed to leave ring gracefully", "err", err)
+		status = exitShutdownError
+	}
+	if err := store.Close(); err != nil {
+		logger.Error("Failed to close key-value store", "err", err)
+		status = exitShutdownError
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	logger.Info("Draining HTTP Server", "timeout", config.ShutdownTimeout)
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("Failed to drain HTTP Server", "err", err)
+		status = exitShutdownError
+	}
+
+	return status
 }
 
 func main() {
 
-	InitLogger()
 	flag.Parse()
+	if err := InitConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitConfigError)
+	}
+	if err := InitLogger(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitConfigError)
+	}
+	logConfig()
 
-	switch *NodeMode {
-	case "create":
-		CreateStrategy()
-		break
-	case "join":
-		JoinStrategy()
-		break
-	default:
-		break
+	if err := InitTLS(); err != nil {
+		logger.Error("Failed to load TLS configuration", "err", err)
+		os.Exit(exitConfigError)
 	}
 
-	InitHttpServer()
-	for {
+	if config.Mode == "walk" {
+		os.Exit(WalkRing())
+	}
+
+	if err := InitAuth(); err != nil {
+		logger.Error("Failed to load HTTP credentials", "err", err)
+		os.Exit(exitConfigError)
+	}
+
+	if err := InitDiscovery(); err != nil {
+		logger.Error("Failed to initialize discovery", "discovery", config.Discovery, "err", err)
+		os.Exit(exitConfigError)
+	}
+
+	store = NewStore()
+
+	var err error
+	ring, err = NewRing()
+	if err != nil {
+		logger.Error("Failed to start VNode workers", "err", err)
+		os.Exit(exitStartupError)
+	}
+	if err := store.Attach(ring); err != nil {
+		logger.Error("Failed to start key-value store", "err", err)
+		os.Exit(exitStartupError)
+	}
+	pubsub = Scribe.New(ring, Scribe.DefaultRefreshInterval)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	server, serverErrs := InitHttpServer()
+
+	// Start the workers in the background so that signals are handled
+	// and readiness is served while the ring is joined.
+	startErrs := make(chan error, 1)
+	go func() {
+		switch config.Mode {
+		case "create":
+			startErrs <- CreateStrategy()
+		case "join":
+			startErrs <- JoinStrategy()
+		}
+	}()
+
+	status := exitOK
+	for running := true; running; {
+		select {
+		case err := <-startErrs:
+			if err != nil {
+				logger.Error("Failed to start VNode workers", "err", err)
+				status = exitStartupError
+				running = false
+				continue
+			}
+			logger.Info("All VNode workers joined the ring", "workers", config.Workers)
+			RegisterWorkers()
+		case sig := <-signals:
+			logger.Info("Received signal, shutting down", "signal", sig)
+			running = false
+		case <-leaveRequests:
+			logger.Info("Leave requested through the HTTP API, shutting down")
+			running = false
+		case err := <-serverErrs:
+			logger.Error("HTTP Server stopped, shutting down", "err", err)
+			status = exitServerError
+			running = false
+		}
+	}
 
+	if shutdownStatus := Shutdown(server); status == exitOK {
+		status = shutdownStatus
 	}
 
+	logger.Info("Stopped", "status", status)
+	os.Exit(status)
 }
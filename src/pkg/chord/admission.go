@@ -0,0 +1,173 @@
+package chord
+
+// Admission control.
+// Requests are admitted by token buckets refilled at a rate per peer address
+// and for all peers together, and connections beyond a maximum are shed.
+// Shed requests fail with ErrOverloaded, which tells callers the server is
+// alive and that they should back off or route elsewhere.
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrOverloaded is returned for requests shed by admission control.
+var ErrOverloaded = errors.New("chord: overloaded, retry later")
+
+// maxPeerBuckets bounds the number of peers rate limited individually.
+const maxPeerBuckets = 4096
+
+// AdmissionPolicy limits the load a server accepts.
+type AdmissionPolicy struct {
+	// PeerRate is the number of requests per second admitted from a single peer address, 0 admits all.
+	PeerRate float64
+	// GlobalRate is the number of requests per second admitted from all peers together, 0 admits all.
+	GlobalRate float64
+	// Burst is the number of requests admitted at once beyond the rates.
+	Burst int
+	// MaxConns bounds the number of concurrent connections, 0 admits all.
+	MaxConns int
+}
+
+// validate checks the policy for negative limits.
+func (p AdmissionPolicy) validate() error {
+	if p.PeerRate < 0 || p.GlobalRate < 0 || p.Burst < 0 || p.MaxConns < 0 {
+		return errors.New("chord: admission limits must not be negative")
+	}
+	return nil
+}
+
+// bucket is a token bucket, one token admits one request.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accrued at rate since the last refill, up to burst.
+func (b *bucket) refill(rate float64, burst float64, now time.Time) {
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * rate
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+	}
+	b.last = now
+}
+
+// Admission admits requests and connections according to an AdmissionPolicy.
+// It is safe for concurrent use and may be shared by several servers.
+type Admission struct {
+	policy AdmissionPolicy
+	burst  float64
+
+	mu     sync.Mutex
+	global bucket
+	peers  map[string]*bucket
+	conns  int
+}
+
+// NewAdmission creates an Admission enforcing policy.
+func NewAdmission(policy AdmissionPolicy) *Admission {
+	burst := float64(policy.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Admission{
+		policy: policy,
+		burst:  burst,
+		peers:  make(map[string]*bucket),
+	}
+}
+
+// Allow reports whether a request from the peer at addr is admitted.
+// Peers are told apart by host, the port of addr is ignored.
+func (a *Admission) Allow(addr string) bool {
+	if a == nil || (a.policy.PeerRate == 0 && a.policy.GlobalRate == 0) {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var peer *bucket
+	if a.policy.PeerRate > 0 {
+		peer = a.peers[host]
+		if peer == nil {
+			a.prunePeers(now)
+			peer = &bucket{}
+			a.peers[host] = peer
+		}
+		peer.refill(a.policy.PeerRate, a.burst, now)
+		if peer.tokens < 1 {
+			return false
+		}
+	}
+	if a.policy.GlobalRate > 0 {
+		a.global.refill(a.policy.GlobalRate, a.burst, now)
+		if a.global.tokens < 1 {
+			return false
+		}
+		a.global.tokens--
+	}
+	if peer != nil {
+		peer.tokens--
+	}
+	return true
+}
+
+// prunePeers makes room for a new peer by forgetting peers whose buckets
+// refilled completely, or all peers if none did.
+func (a *Admission) prunePeers(now time.Time) {
+	if len(a.peers) < maxPeerBuckets {
+		return
+	}
+
+	for host, peer := range a.peers {
+		peer.refill(a.policy.PeerRate, a.burst, now)
+		if peer.tokens >= a.burst {
+			delete(a.peers, host)
+		}
+	}
+	if len(a.peers) >= maxPeerBuckets {
+		a.peers = make(map[string]*bucket)
+	}
+}
+
+// Acquire reserves a connection, it reports false if MaxConns connections are open.
+// Every acquired connection must be released.
+func (a *Admission) Acquire() bool {
+	if a == nil || a.policy.MaxConns == 0 {
+		return true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.conns >= a.policy.MaxConns {
+		return false
+	}
+	a.conns++
+	return true
+}
+
+// Release frees a connection reserved by Acquire.
+func (a *Admission) Release() {
+	if a == nil || a.policy.MaxConns == 0 {
+		return
+	}
+
+	a.mu.Lock()
+	a.conns--
+	a.mu.Unlock()
+}
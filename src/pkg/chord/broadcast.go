@@ -0,0 +1,187 @@
+package chord
+
+// Ring wide broadcast.
+// A VNode receiving a broadcast covers the identifier range from itself up to
+// the message's limit. It splits the range at its distinct fingers within it
+// and forwards the message to every such finger, limited by the next one.
+// Every VNode receives the message once and the ring is covered in O(log N) rounds.
+// If a VNode cannot be reached, its range is forwarded to the next reachable
+// VNode within it instead.
+// Replies are aggregated on the way back, so the originator receives one reply
+// for the whole ring.
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	Util "github.com/arush15june/chord-golang/src/pkg/util"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// BroadcastMessage is an opaque application payload sent to every VNode of the ring.
+type BroadcastMessage = VNode.BroadcastMessage
+
+// BroadcastReply aggregates the replies of the VNodes a broadcast reached.
+type BroadcastReply = VNode.BroadcastReply
+
+// BroadcastApplication receives the upcalls for messages broadcast on the ring.
+// It must be registered under the same name on every Ring receiving its broadcasts.
+type BroadcastApplication interface {
+	// Receive is called once on every VNode reached and returns the VNode's reply.
+	Receive(local *LocalVNode, msg *BroadcastMessage) []byte
+
+	// Aggregate combines the replies of a VNode and the VNodes it forwarded to.
+	// Replies of VNodes which could not be reached are missing.
+	Aggregate(replies [][]byte) []byte
+}
+
+// RegisterBroadcast registers app to handle messages broadcast for the named application.
+func (ring *Ring) RegisterBroadcast(name string, app BroadcastApplication) {
+	ring.appsMu.Lock()
+	defer ring.appsMu.Unlock()
+
+	ring.broadcastApps[name] = app
+}
+
+// broadcastApplication returns the BroadcastApplication registered under name.
+func (ring *Ring) broadcastApplication(name string) (BroadcastApplication, error) {
+	ring.appsMu.RLock()
+	defer ring.appsMu.RUnlock()
+
+	app, ok := ring.broadcastApps[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown broadcast application %q", name)
+	}
+	return app, nil
+}
+
+// Broadcast sends payload for the named application to every VNode of the ring.
+// It returns once every reachable VNode has replied.
+func (ring *Ring) Broadcast(application string, payload []byte) (*BroadcastReply, error) {
+	if !ring.Ready() {
+		return nil, ErrNotReady
+	}
+
+	origin := ring.vnodes[0]
+	return origin.Broadcast(&BroadcastMessage{
+		Application: application,
+		Payload:     payload,
+		Origin:      origin.Hostname(),
+		Limit:       origin.ID(),
+	})
+}
+
+// broadcastTargets returns the distinct successor and fingers within (node, limit)
+// ordered clockwise from the VNode.
+func (node *LocalVNode) broadcastTargets(limit uint64) []VNode.VNodeProtocol {
+	candidates := append([]VNode.VNodeProtocol{node.successor()}, node.fingerTable()...)
+
+	seen := make(map[uint64]bool)
+	targets := make([]VNode.VNodeProtocol, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate == nil || seen[candidate.ID()] {
+			continue
+		}
+		seen[candidate.ID()] = true
+
+		if candidate.ID() != limit && candidate.ID() != node.ID() && Util.IsBetweenID(candidate.ID(), node.ID(), limit) {
+			targets = append(targets, candidate)
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].ID()-node.ID() < targets[j].ID()-node.ID()
+	})
+	return targets
+}
+
+// nextBroadcastTarget returns the closest VNode following after within (after, limit),
+// from the successors and fingers of the VNode or else a lookup. It returns nil if there is none.
+func (node *LocalVNode) nextBroadcastTarget(after uint64, limit uint64) VNode.VNodeProtocol {
+	within := func(candidate VNode.VNodeProtocol) bool {
+		return candidate != nil && candidate.ID() != after && candidate.ID() != limit &&
+			candidate.ID() != node.ID() && Util.IsBetweenID(candidate.ID(), after, limit)
+	}
+
+	var next VNode.VNodeProtocol
+	for _, candidate := range append(node.successorList(), node.fingerTable()...) {
+		if within(candidate) && (next == nil || candidate.ID()-after < next.ID()-after) {
+			next = candidate
+		}
+	}
+	if next != nil {
+		return next
+	}
+
+	successor, err := node.FindSuccessor(after + 1)
+	if err != nil || !within(successor) {
+		return nil
+	}
+	return successor
+}
+
+// forwardBroadcast forwards msg to target, or to the next reachable VNode up
+// to msg.Limit while forwarding fails. It returns the reply, nil if no VNode
+// was reached, and the number of VNodes which could not be reached.
+func (node *LocalVNode) forwardBroadcast(target VNode.VNodeProtocol, msg *BroadcastMessage) (*BroadcastReply, int) {
+	failed := 0
+	for target != nil {
+		reply, err := target.Broadcast(msg)
+		if err == nil {
+			return reply, failed
+		}
+		node.log.Warn("Failed to forward broadcast", "application", msg.Application, "target", target.Hostname(), "limit", msg.Limit, "err", err)
+		failed++
+		target = node.nextBroadcastTarget(target.ID(), msg.Limit)
+	}
+	return nil, failed
+}
+
+// Broadcast calls the Receive upcall of msg's application and forwards msg
+// to the VNodes between the VNode and msg.Limit, aggregating their replies.
+func (node *LocalVNode) Broadcast(msg *BroadcastMessage) (*BroadcastReply, error) {
+	app, err := node.ring.broadcastApplication(msg.Application)
+	if err != nil {
+		return nil, err
+	}
+
+	node.log.Debug("Received broadcast", "application", msg.Application, "origin", msg.Origin, "limit", msg.Limit, "depth", msg.Depth)
+	own := app.Receive(node, msg)
+
+	targets := node.broadcastTargets(msg.Limit)
+	replies := make([]*BroadcastReply, len(targets))
+	failed := make([]int, len(targets))
+
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		forward := *msg
+		forward.Depth++
+		if i+1 < len(targets) {
+			forward.Limit = targets[i+1].ID()
+		}
+
+		wg.Add(1)
+		go func(i int, target VNode.VNodeProtocol, forward *BroadcastMessage) {
+			defer wg.Done()
+
+			replies[i], failed[i] = node.forwardBroadcast(target, forward)
+		}(i, target, &forward)
+	}
+	wg.Wait()
+
+	result := &BroadcastReply{Acks: 1}
+	payloads := [][]byte{own}
+	for i, reply := range replies {
+		result.Failed += failed[i]
+		if reply == nil {
+			continue
+		}
+		result.Acks += reply.Acks
+		result.Failed += reply.Failed
+		payloads = append(payloads, reply.Payload)
+	}
+	result.Payload = app.Aggregate(payloads)
+
+	return result, nil
+}
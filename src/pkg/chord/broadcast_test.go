@@ -0,0 +1,183 @@
+package chord
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fastRing creates a ring of n VNodes with k successors converging quickly.
+func fastRing(t *testing.T, n int, k int, opts ...Option) *Ring {
+	return testRing(t, append([]Option{
+		WithVNodes(n),
+		WithSuccessors(k),
+		WithStabilizeInterval(20*time.Millisecond, 50*time.Millisecond),
+		WithFixFingerInterval(10 * time.Millisecond),
+		WithMaxFixFingerInterval(50 * time.Millisecond),
+	}, opts...)...)
+}
+
+// converged reports whether the predecessors and successor lists of vnodes
+// are those of a ring made of exactly vnodes.
+func converged(vnodes []*LocalVNode) bool {
+	sorted := append([]*LocalVNode(nil), vnodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID() < sorted[j].ID() })
+
+	n := len(sorted)
+	for i, vnode := range sorted {
+		predecessor := vnode.currentPredecessor()
+		if predecessor == nil || predecessor.Hostname() != sorted[(i+n-1)%n].Hostname() {
+			return false
+		}
+		successors := vnode.successorList()
+		want := vnode.maxSuccessors
+		if want > n-1 {
+			want = n - 1
+		}
+		if len(successors) < want {
+			return false
+		}
+		for j := 0; j < want; j++ {
+			if successors[j].Hostname() != sorted[(i+1+j)%n].Hostname() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// waitForRing waits until the VNodes of rings form one converged ring.
+func waitForRing(t *testing.T, rings ...*Ring) []*LocalVNode {
+	t.Helper()
+	var vnodes []*LocalVNode
+	for _, ring := range rings {
+		vnodes = append(vnodes, ring.VNodes()...)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !converged(vnodes) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d VNodes did not converge to a ring", len(vnodes))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return vnodes
+}
+
+// countingApp counts the broadcasts received by every VNode and replies
+// with the VNode's hostname.
+type countingApp struct {
+	mu       sync.Mutex
+	received map[string]int
+}
+
+func (app *countingApp) Receive(local *LocalVNode, msg *BroadcastMessage) []byte {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	app.received[local.Hostname()]++
+	return []byte(local.Hostname())
+}
+
+func (app *countingApp) Aggregate(replies [][]byte) []byte {
+	hostnames := make([]string, 0, len(replies))
+	for _, reply := range replies {
+		if len(reply) > 0 {
+			hostnames = append(hostnames, string(reply))
+		}
+	}
+	return []byte(strings.Join(hostnames, "\n"))
+}
+
+// count returns the number of broadcasts received by the VNode at hostname and resets it.
+func (app *countingApp) count(hostname string) int {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	n := app.received[hostname]
+	delete(app.received, hostname)
+	return n
+}
+
+// broadcastRings creates two converged rings sharing a counting broadcast application.
+func broadcastRings(t *testing.T) (*Ring, *Ring, *countingApp) {
+	app := &countingApp{received: make(map[string]int)}
+	first := fastRing(t, 5, 3)
+	first.RegisterBroadcast("count", app)
+	if err := first.Create(); err != nil {
+		t.Fatal(err)
+	}
+	second := fastRing(t, 3, 3)
+	second.RegisterBroadcast("count", app)
+	if err := second.Join(first.Hostnames()[0]); err != nil {
+		first.Leave()
+		t.Fatal(err)
+	}
+	waitForRing(t, first, second)
+	return first, second, app
+}
+
+func TestBroadcastReachesEveryVNodeOnce(t *testing.T) {
+	first, second, app := broadcastRings(t)
+	defer first.Leave()
+	defer second.Leave()
+
+	reply, err := first.Broadcast("count", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Acks != 8 || reply.Failed != 0 {
+		t.Errorf("broadcast acked by %d VNodes, %d failed, want 8 and 0", reply.Acks, reply.Failed)
+	}
+
+	replied := strings.Split(string(reply.Payload), "\n")
+	sort.Strings(replied)
+	hostnames := append(first.Hostnames(), second.Hostnames()...)
+	sort.Strings(hostnames)
+	if strings.Join(replied, ",") != strings.Join(hostnames, ",") {
+		t.Errorf("aggregated replies of %q, want %q", replied, hostnames)
+	}
+	for _, hostname := range hostnames {
+		if n := app.count(hostname); n != 1 {
+			t.Errorf("%s received the broadcast %d times, want once", hostname, n)
+		}
+	}
+}
+
+func TestBroadcastForwardsPastFailedTargets(t *testing.T) {
+	first, second, app := broadcastRings(t)
+	defer first.Leave()
+	defer second.Leave()
+
+	// The second ring crashes, its VNodes are still in the routing tables.
+	second.closeServers()
+	// Broadcast from the VNode preceding a failed one, the failed VNode's
+	// range covers the rest of the first ring.
+	failed := second.VNodes()[0]
+	origin := first.VNodes()[0]
+	for _, vnode := range first.VNodes() {
+		if failed.ID()-vnode.ID() < failed.ID()-origin.ID() {
+			origin = vnode
+		}
+	}
+
+	msg := &BroadcastMessage{Application: "count", Origin: origin.Hostname(), Limit: origin.ID()}
+	reply, failures := origin.forwardBroadcast(first.Transport().Remote(failed.Hostname()), msg)
+	if reply == nil || failures < 1 {
+		t.Fatalf("forwardBroadcast() = %v, %d failures, want a reply after at least 1 failure", reply, failures)
+	}
+
+	// The range of the failed target is covered by the VNodes following it.
+	for _, vnode := range first.VNodes() {
+		want := 1
+		if vnode == origin {
+			want = 0
+		}
+		if n := app.count(vnode.Hostname()); n != want {
+			t.Errorf("%s received the broadcast %d times, want %d", vnode.Hostname(), n, want)
+		}
+	}
+	if want := len(first.VNodes()) - 1; reply.Acks != want {
+		t.Errorf("broadcast acked by %d VNodes, want %d", reply.Acks, want)
+	}
+}
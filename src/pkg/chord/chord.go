@@ -0,0 +1,434 @@
+package chord
+
+// Implements Chord Node,
+// Initiates the VNodes for the current physical node,
+// Initiates the RPC Transport Listener,
+// Handles requests from VNodes to the RPC transport,
+// Delegates requests to VNodes from the RPC Transport
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+	Util "github.com/arush15june/chord-golang/src/pkg/util"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// ErrNotReady is returned by operations requiring all VNodes to have joined the ring.
+var ErrNotReady = errors.New("VNodes have not joined the ring yet")
+
+// Ring is a set of local VNodes participating in a Chord ring.
+// Each VNode serves the Chord protocol over its own RPC server.
+type Ring struct {
+	options   Options
+	log       *Logging.Logger
+	transport *Transport
+	detector  *FailureDetector
+	claims    *claims
+
+	vnodes  []*LocalVNode
+	servers []*ChordTCPRPCServer
+
+	appsMu        sync.RWMutex
+	apps          map[string]Application
+	broadcastApps map[string]BroadcastApplication
+
+	// seedHostnames are the seeds passed to Join, kept for re-joining.
+	seedHostnames []string
+
+	// savedStates are the routing states of the VNodes saved before a restart.
+	savedStates []*vnodeState
+	stopPersist chan struct{}
+
+	violationsMu     sync.Mutex
+	violationCounts  map[string]uint64
+	recentViolations []Violation
+
+	// ready is set to 1 once all VNodes have joined the ring.
+	ready int32
+}
+
+// New initializes the VNodes of a Ring and starts their RPC servers.
+// The VNodes take part in a ring once Create or Join is called.
+func New(opts ...Option) (*Ring, error) {
+	options := DefaultOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	ring := &Ring{
+		options:       options,
+		log:           options.Logs.Logger("ring").With("hostname", options.Hostname),
+		apps:          make(map[string]Application),
+		broadcastApps: make(map[string]BroadcastApplication),
+
+		violationCounts: make(map[string]uint64),
+
+		claims: newClaims(),
+
+		stopPersist: make(chan struct{}),
+	}
+	ring.RegisterBroadcast(aggregateApplication, aggregator{})
+	ring.transport = newTransport(options.Logs.Logger("rpc"), options.DialTimeout, options.CallTimeout, options.TLS)
+	if options.Identity != nil {
+		ids, err := newIdentities(*options.Identity, options.IdentityRoots)
+		if err != nil {
+			return nil, err
+		}
+		ring.transport.identities = ids
+	}
+	ring.transport.admission = NewAdmission(options.Admission)
+	ring.detector = NewFailureDetector(options.PhiThreshold, options.CheckPredInterval)
+
+	if err := ring.loadStates(); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < options.VNodes; i++ {
+		if _, err := ring.newVNodeWithRPC(i); err != nil {
+			ring.closeServers()
+			return nil, err
+		}
+	}
+
+	return ring, nil
+}
+
+// validate checks options for values the protocol cannot run with.
+func (o Options) validate() error {
+	if o.VNodes < 1 {
+		return fmt.Errorf("chord: at least one VNode is required, got %d", o.VNodes)
+	}
+	if o.MinStabilizeInterval <= 0 || o.MaxStabilizeInterval < o.MinStabilizeInterval {
+		return fmt.Errorf("chord: invalid stabilize interval [%s, %s]", o.MinStabilizeInterval, o.MaxStabilizeInterval)
+	}
+	if o.FixFingerInterval <= 0 || o.MaxFixFingerInterval < o.FixFingerInterval {
+		return fmt.Errorf("chord: invalid fix finger interval [%s, %s]", o.FixFingerInterval, o.MaxFixFingerInterval)
+	}
+	if o.CheckPredInterval <= 0 {
+		return errors.New("chord: check predecessor interval must be positive")
+	}
+	if o.PhiThreshold <= 0 {
+		return fmt.Errorf("chord: phi threshold must be positive, got %g", o.PhiThreshold)
+	}
+	if o.VerifyInterval < 0 {
+		return errors.New("chord: verify interval must not be negative")
+	}
+	if o.MaxSuccessors < 1 {
+		return fmt.Errorf("chord: at least one successor is required, got %d", o.MaxSuccessors)
+	}
+	if o.MaxFingers < 1 || o.MaxFingers > 64 {
+		return fmt.Errorf("chord: fingers must be between 1 and 64, got %d", o.MaxFingers)
+	}
+	if o.Logs == nil {
+		return errors.New("chord: log registry is required")
+	}
+	if err := o.Admission.validate(); err != nil {
+		return err
+	}
+	if o.Identity != nil && o.VNodes > MaxIdentityVNodes {
+		return fmt.Errorf("chord: identities are limited to %d VNodes", MaxIdentityVNodes)
+	}
+	if o.TLS != nil && len(o.TLS.Certificates) == 0 && o.TLS.GetCertificate == nil {
+		return errors.New("chord: TLS requires a certificate for the RPC servers")
+	}
+	if o.Delegate == nil {
+		return errors.New("chord: delegate is required, use NopDelegate for none")
+	}
+	if o.StateDir != "" && o.PersistInterval <= 0 {
+		return errors.New("chord: persist interval must be positive")
+	}
+	if o.CallTimeout <= 0 {
+		return errors.New("chord: call timeout must be positive")
+	}
+
+	return nil
+}
+
+// newVNodeWithRPC initializes the index'th LocalVNode and starts a ChordTCPRPCServer on it.
+// The server listens on the hostname saved before a restart if it is still
+// available, so that the VNode keeps its ID.
+func (ring *Ring) newVNodeWithRPC(index int) (*LocalVNode, error) {
+	ring.log.Debug("Initializing New Local VNode")
+
+	hostname := ring.options.Hostname
+	saved := ring.savedHostname(index)
+	if reusable(saved, hostname) {
+		hostname = saved
+	}
+
+	vnode := newLocalVNode(hostname, index, ring)
+
+	rpc := InitChordTCPRPCServer(hostname, vnode, ring.transport)
+	err := InitServer(rpc)
+	if err != nil && hostname != ring.options.Hostname {
+		ring.log.Warn("Saved hostname is unavailable, the VNode takes a new ID", "saved_hostname", hostname)
+		rpc = InitChordTCPRPCServer(ring.options.Hostname, vnode, ring.transport)
+		err = InitServer(rpc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ring.servers = append(ring.servers, rpc)
+	if err := vnode.SetHostname(rpc.Hostname); err != nil {
+		rpc.Close()
+		return nil, err
+	}
+	ring.vnodes = append(ring.vnodes, vnode)
+
+	ring.log.Info("RPC Server Initialized", "vnode", vnode.ID(), "vnode_hostname", vnode.Hostname())
+
+	return vnode, nil
+}
+
+// VNodes returns the local VNodes of the ring.
+func (ring *Ring) VNodes() []*LocalVNode {
+	return ring.vnodes
+}
+
+// LocalVNode returns the local VNode listening on hostname, nil if the VNode is not local.
+func (ring *Ring) LocalVNode(hostname string) *LocalVNode {
+	for _, vnode := range ring.vnodes {
+		if vnode.Hostname() == hostname {
+			return vnode
+		}
+	}
+
+	return nil
+}
+
+// Hostnames returns the RPC hostnames of the local VNodes.
+func (ring *Ring) Hostnames() []string {
+	hostnames := make([]string, len(ring.vnodes))
+	for i, vnode := range ring.vnodes {
+		hostnames[i] = vnode.Hostname()
+	}
+
+	return hostnames
+}
+
+// Transport returns the transport used to reach remote VNodes.
+func (ring *Ring) Transport() *Transport {
+	return ring.transport
+}
+
+// Detector returns the failure detector judging the liveness of remote VNodes.
+func (ring *Ring) Detector() *FailureDetector {
+	return ring.detector
+}
+
+// Logs returns the log registry of the ring, for applications running on it.
+func (ring *Ring) Logs() *Logging.Registry {
+	return ring.options.Logs
+}
+
+// Ready reports whether all local VNodes have joined the ring.
+func (ring *Ring) Ready() bool {
+	return atomic.LoadInt32(&ring.ready) == 1
+}
+
+// joinWithRetry joins vnode to the ring through the first responsive seed.
+// Every round tries the seeds in order, rounds are separated by an
+// exponential backoff until the deadline passes. No seed is tried after the
+// deadline, and a seed which does not reply fails after the call timeout.
+func (ring *Ring) joinWithRetry(vnode *LocalVNode, seeds func() []VNode.VNodeProtocol, deadline time.Time) error {
+	policy := ring.options.JoinPolicy
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := errors.New("no seeds available")
+		for _, seed := range seeds() {
+			if time.Now().After(deadline) {
+				break
+			}
+			if err = vnode.Join(seed); err == nil {
+				return nil
+			}
+			ring.log.Warn("Failed to join through seed", "vnode", vnode.ID(), "seed", seed.Hostname(), "attempt", attempt, "err", err)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("join timed out after %d attempts: %v", attempt, err)
+		}
+
+		wait := Util.GetRandomDurationBetween(backoff/2, backoff)
+		if wait > remaining {
+			wait = remaining
+		}
+		time.Sleep(wait)
+
+		backoff *= 2
+		if backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// joinVNodes joins the VNodes concurrently through seeds and starts them.
+// seeds is evaluated on every round of attempts.
+// It blocks until every VNode joined or the join policy timeout elapsed.
+func (ring *Ring) joinVNodes(joining []*LocalVNode, seeds func() []VNode.VNodeProtocol) error {
+	deadline := time.Now().Add(ring.options.JoinPolicy.Timeout)
+	errs := make(chan error, len(joining))
+
+	for _, vnode := range joining {
+		go func(vnode *LocalVNode) {
+			if err := ring.joinWithRetry(vnode, seeds, deadline); err != nil {
+				errs <- fmt.Errorf("vnode %s: %v", vnode.Hostname(), err)
+				return
+			}
+			vnode.InitializeFingerTables()
+			vnode.StartWorker()
+			errs <- nil
+		}(vnode)
+	}
+
+	var firstErr error
+	for range joining {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Join joins the VNodes to an existing chord ring through any of the seed
+// hostnames, or the ones returned by the SeedSource option. The peers saved
+// before a restart are tried first on every round of attempts, then the
+// SeedSource, then the seed hostnames. It blocks until all VNodes have joined
+// or the join policy timeout elapsed.
+func (ring *Ring) Join(seedHostnames ...string) error {
+	known := ring.knownPeers()
+	ring.log.Info("Joining Existing Ring", "vnodes", len(ring.vnodes), "seeds", len(seedHostnames), "known_peers", len(known))
+
+	ring.seedHostnames = seedHostnames
+	seeds := func() []VNode.VNodeProtocol {
+		return append(known, ring.remotes(ring.seeds())...)
+	}
+
+	if err := ring.joinVNodes(ring.vnodes, seeds); err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(&ring.ready, 1)
+	ring.startPersisting()
+	return nil
+}
+
+// seeds returns the hostnames of the SeedSource followed by the seeds passed to Join.
+func (ring *Ring) seeds() []string {
+	hostnames := make([]string, 0, len(ring.seedHostnames))
+	if ring.options.SeedSource != nil {
+		hostnames = append(hostnames, ring.options.SeedSource()...)
+	}
+	return append(hostnames, ring.seedHostnames...)
+}
+
+// rejoinSeeds returns the seeds which are not local VNodes,
+// the VNodes re-join through when disconnected from them.
+func (ring *Ring) rejoinSeeds() []VNode.VNodeProtocol {
+	hostnames := make([]string, 0)
+	for _, hostname := range ring.seeds() {
+		if ring.LocalVNode(hostname) == nil {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	return ring.remotes(hostnames)
+}
+
+// remotes returns the remote VNodes listening on hostnames.
+func (ring *Ring) remotes(hostnames []string) []VNode.VNodeProtocol {
+	remotes := make([]VNode.VNodeProtocol, len(hostnames))
+	for i, hostname := range hostnames {
+		remotes[i] = ring.transport.Remote(hostname)
+	}
+	return remotes
+}
+
+// Create creates a Chord ring in one of the local VNodes
+// and joins all other local VNodes to it.
+// If a peer saved before a restart responds, the VNodes rejoin its ring instead.
+func (ring *Ring) Create() error {
+	first := ring.vnodes[0]
+	if known := ring.knownPeers(); len(known) > 0 {
+		ring.log.Info("Rejoining Ring", "vnodes", len(ring.vnodes), "known_peers", len(known))
+
+		// Roughly a single round of attempts, a ring which vanished is not waited for.
+		deadline := time.Now().Add(ring.options.CallTimeout * time.Duration(len(known)))
+		err := ring.joinWithRetry(first, func() []VNode.VNodeProtocol { return known }, deadline)
+		if err == nil {
+			first.InitializeFingerTables()
+			first.StartWorker()
+			return ring.joinThrough(first, known...)
+		}
+		ring.log.Warn("Known peers did not respond, creating a new ring", "known_peers", len(known), "err", err)
+	}
+
+	ring.log.Info("Creating New Ring", "vnodes", len(ring.vnodes))
+
+	first.Create()
+	first.StartWorker()
+	return ring.joinThrough(first)
+}
+
+// joinThrough joins the VNodes other than first through first and peers.
+func (ring *Ring) joinThrough(first *LocalVNode, peers ...VNode.VNodeProtocol) error {
+	seeds := func() []VNode.VNodeProtocol {
+		return append([]VNode.VNodeProtocol{first}, peers...)
+	}
+	if err := ring.joinVNodes(ring.vnodes[1:], seeds); err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(&ring.ready, 1)
+	ring.startPersisting()
+	return nil
+}
+
+// Lookup returns the hostname of the VNode responsible for Key.
+func (ring *Ring) Lookup(Key string) (string, error) {
+	if !ring.Ready() {
+		return "", ErrNotReady
+	}
+	return ring.vnodes[0].Lookup(Key)
+}
+
+// Leave gracefully removes all local VNodes from the ring one after
+// another and closes their RPC servers. It returns the first error encountered.
+func (ring *Ring) Leave() error {
+	ring.stopPersisting()
+	atomic.StoreInt32(&ring.ready, 0)
+
+	var firstErr error
+	for _, vnode := range ring.vnodes {
+		if err := vnode.Leave(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if err := ring.closeServers(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	return firstErr
+}
+
+// closeServers closes the RPC servers of all VNodes.
+func (ring *Ring) closeServers() error {
+	var firstErr error
+	for _, server := range ring.servers {
+		if err := server.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
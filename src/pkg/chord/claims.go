@@ -0,0 +1,113 @@
+package chord
+
+// Validation of routing claims.
+// VNode references arrive from other VNodes, which may be stale or lie.
+// Before a VNode is adopted as predecessor, successor or finger, it is pinged
+// to confirm it is reachable and reports the claimed ID at the claimed hostname.
+// Successors found by lookups are also checked to own the looked up ID.
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	Util "github.com/arush15june/chord-golang/src/pkg/util"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// ErrInvalidClaim is returned for VNode references which do not check out.
+var ErrInvalidClaim = errors.New("chord: invalid VNode claim")
+
+// claimTTL is how long a confirmed VNode is trusted without pinging it again.
+const claimTTL = time.Minute
+
+// maxConfirmedClaims bounds the cache of confirmed VNodes.
+const maxConfirmedClaims = 4096
+
+// confirmation is a VNode confirmed to run at its hostname.
+type confirmation struct {
+	id uint64
+	at time.Time
+}
+
+// claims caches the VNodes confirmed by the VNodes of a Ring.
+type claims struct {
+	mu        sync.Mutex
+	confirmed map[string]confirmation
+}
+
+func newClaims() *claims {
+	return &claims{confirmed: make(map[string]confirmation)}
+}
+
+func (c *claims) isConfirmed(vnode VNode.VNodeProtocol) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	confirmed, ok := c.confirmed[vnode.Hostname()]
+	return ok && confirmed.id == vnode.ID() && time.Since(confirmed.at) < claimTTL
+}
+
+func (c *claims) confirm(vnode VNode.VNodeProtocol) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.confirmed) >= maxConfirmedClaims {
+		c.confirmed = make(map[string]confirmation)
+	}
+	c.confirmed[vnode.Hostname()] = confirmation{id: vnode.ID(), at: time.Now()}
+}
+
+// confirm checks that vnode is reachable and runs a VNode with the claimed ID
+// at its hostname. Local VNodes are checked against the Ring.
+func (node *LocalVNode) confirm(vnode VNode.VNodeProtocol) error {
+	if local := node.ring.LocalVNode(vnode.Hostname()); local != nil {
+		if local.ID() != vnode.ID() {
+			return fmt.Errorf("%v: local VNode %s has ID %d, not %d", ErrInvalidClaim, vnode.Hostname(), local.ID(), vnode.ID())
+		}
+		return nil
+	}
+
+	remote, ok := vnode.(*RemoteVNode)
+	if !ok {
+		return fmt.Errorf("%v: %s is neither local nor remote", ErrInvalidClaim, vnode.Hostname())
+	}
+	if node.ring.claims.isConfirmed(remote) {
+		return nil
+	}
+
+	id, hostname, err := remote.Identify()
+	if err != nil {
+		return fmt.Errorf("%v: %s is unreachable: %v", ErrInvalidClaim, remote.Hostname(), err)
+	}
+	if hostname != remote.Hostname() || id != remote.ID() {
+		return fmt.Errorf("%v: %s claims ID %d, but reports %s with ID %d", ErrInvalidClaim, remote.Hostname(), remote.ID(), hostname, id)
+	}
+
+	node.detector().Heartbeat(remote.Hostname())
+	node.ring.claims.confirm(remote)
+	return nil
+}
+
+// confirmSuccessor checks that successor, found by a lookup of id, is a confirmed
+// VNode owning id: id must lie between the successor's predecessor and the successor.
+func (node *LocalVNode) confirmSuccessor(id uint64, successor VNode.VNodeProtocol) error {
+	if successor.ID() == node.ID() {
+		return nil
+	}
+	if err := node.confirm(successor); err != nil {
+		return err
+	}
+
+	predecessor, err := successor.GetPredecessor()
+	if err != nil || predecessor.ID() == successor.ID() || predecessor.ID() == node.ID() {
+		// A VNode without predecessor has not been notified yet and claims nothing,
+		// one preceded by the VNode itself is its successor.
+		return nil
+	}
+	if !Util.IsBetweenID(id, predecessor.ID(), successor.ID()) {
+		return fmt.Errorf("%v: %s is not the successor of %d, its predecessor %s is", ErrInvalidClaim, successor.Hostname(), id, predecessor.Hostname())
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package chord
+
+import (
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// Delegate is notified by the local VNodes of a Ring when the key range they own
+// or their place in the ring changes, so applications can move their data.
+// A LocalVNode owns the keys in (predecessor, vnode].
+// Methods are called synchronously from the protocol goroutines without
+// any lock held, they should return quickly and hand off long running work.
+type Delegate interface {
+	// NewPredecessor is called when the predecessor of local changes.
+	// previous is nil if local had no predecessor, predecessor is nil
+	// if the predecessor left and its own predecessor is unknown.
+	NewPredecessor(local *LocalVNode, previous VNode.VNodeProtocol, predecessor VNode.VNodeProtocol)
+
+	// NewSuccessor is called when the successor of local changes.
+	NewSuccessor(local *LocalVNode, previous VNode.VNodeProtocol, successor VNode.VNodeProtocol)
+
+	// PredecessorFailed is called when the predecessor of local is declared dead.
+	// local takes over the keys of the failed predecessor.
+	PredecessorFailed(local *LocalVNode, predecessor VNode.VNodeProtocol)
+
+	// Leaving is called before local leaves the ring, while its predecessor
+	// and successor still know it. successor takes over the keys of local.
+	Leaving(local *LocalVNode, predecessor VNode.VNodeProtocol, successor VNode.VNodeProtocol)
+
+	// Left is called after local has left the ring.
+	Left(local *LocalVNode)
+}
+
+// NopDelegate implements Delegate with methods that do nothing,
+// embed it to implement only some of the callbacks.
+type NopDelegate struct{}
+
+// NewPredecessor implements Delegate.
+func (NopDelegate) NewPredecessor(*LocalVNode, VNode.VNodeProtocol, VNode.VNodeProtocol) {}
+
+// NewSuccessor implements Delegate.
+func (NopDelegate) NewSuccessor(*LocalVNode, VNode.VNodeProtocol, VNode.VNodeProtocol) {}
+
+// PredecessorFailed implements Delegate.
+func (NopDelegate) PredecessorFailed(*LocalVNode, VNode.VNodeProtocol) {}
+
+// Leaving implements Delegate.
+func (NopDelegate) Leaving(*LocalVNode, VNode.VNodeProtocol, VNode.VNodeProtocol) {}
+
+// Left implements Delegate.
+func (NopDelegate) Left(*LocalVNode) {}
@@ -0,0 +1,151 @@
+package chord
+
+// Phi accrual failure detection.
+// Every successful contact with a remote VNode is a heartbeat, the intervals
+// between heartbeats are kept per VNode. Once a VNode fails to respond, the
+// suspicion phi grows with the time since its last heartbeat, relative to the
+// intervals seen so far, and the VNode is declared dead when phi exceeds the
+// threshold. Transient failures of otherwise regular peers do not cause churn.
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultPhiThreshold is the suspicion above which VNodes are declared dead.
+// A phi of 8 means a 10^-8 probability of a heartbeat arriving this late.
+const DefaultPhiThreshold = 8
+
+// maxHeartbeatSamples is the number of heartbeat intervals kept per VNode.
+const maxHeartbeatSamples = 100
+
+// minHeartbeatStdDev bounds the standard deviation of very regular heartbeats,
+// which would otherwise make a slight delay look like a failure.
+const minHeartbeatStdDev = 100 * time.Millisecond
+
+// heartbeatHistory is the heartbeat record of a remote VNode.
+type heartbeatHistory struct {
+	last time.Time
+	// intervals is a ring buffer of heartbeat intervals in seconds.
+	intervals []float64
+	next      int
+	sum       float64
+	sumSq     float64
+	// failing is set when the last contact failed.
+	failing bool
+}
+
+func (h *heartbeatHistory) add(interval float64) {
+	if len(h.intervals) < maxHeartbeatSamples {
+		h.intervals = append(h.intervals, interval)
+	} else {
+		old := h.intervals[h.next]
+		h.sum -= old
+		h.sumSq -= old * old
+		h.intervals[h.next] = interval
+		h.next = (h.next + 1) % maxHeartbeatSamples
+	}
+	h.sum += interval
+	h.sumSq += interval * interval
+}
+
+// phi returns the suspicion of the VNode at now, with the intervals
+// modelled by a normal distribution.
+func (h *heartbeatHistory) phi(now time.Time) float64 {
+	n := float64(len(h.intervals))
+	mean := h.sum / n
+	stdDev := math.Sqrt(math.Max(h.sumSq/n-mean*mean, 0))
+	stdDev = math.Max(stdDev, minHeartbeatStdDev.Seconds())
+
+	// Logistic approximation of the normal CDF, which stays finite far in the tail.
+	y := (now.Sub(h.last).Seconds() - mean) / stdDev
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if y > 0 {
+		return -math.Log10(e / (1 + e))
+	}
+	return -math.Log10(1 - 1/(1+e))
+}
+
+// FailureDetector is a phi accrual failure detector shared by the VNodes of a Ring.
+type FailureDetector struct {
+	threshold float64
+	// bootstrap is the interval assumed for VNodes heard from only once.
+	bootstrap time.Duration
+	// now returns the current time, heartbeats and suspicion are measured with it.
+	now func() time.Time
+
+	mu    sync.Mutex
+	peers map[string]*heartbeatHistory
+}
+
+// NewFailureDetector creates a FailureDetector declaring VNodes dead above threshold.
+// bootstrap is the heartbeat interval assumed until a second heartbeat arrives.
+func NewFailureDetector(threshold float64, bootstrap time.Duration) *FailureDetector {
+	return &FailureDetector{
+		threshold: threshold,
+		bootstrap: bootstrap,
+		now:       time.Now,
+		peers:     make(map[string]*heartbeatHistory),
+	}
+}
+
+// Heartbeat records a successful contact with the VNode at hostname.
+func (d *FailureDetector) Heartbeat(hostname string) {
+	now := d.now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	h, ok := d.peers[hostname]
+	if !ok {
+		h = &heartbeatHistory{}
+		h.add(d.bootstrap.Seconds())
+		d.peers[hostname] = h
+	} else {
+		h.add(now.Sub(h.last).Seconds())
+	}
+	h.last = now
+	h.failing = false
+}
+
+// Phi returns the suspicion of the VNode at hostname, 0 for VNodes responding
+// to the last contact and VNodes never heard from.
+func (d *FailureDetector) Phi(hostname string) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	h, ok := d.peers[hostname]
+	if !ok || !h.failing {
+		return 0
+	}
+	return h.phi(d.now())
+}
+
+// Suspect reports whether the VNode at hostname failed to respond and its
+// suspicion exceeds the threshold.
+func (d *FailureDetector) Suspect(hostname string) bool {
+	return d.Phi(hostname) > d.threshold
+}
+
+// Failed records a failed contact with the VNode at hostname and reports
+// whether it is to be declared dead. VNodes never heard from are dead at once.
+func (d *FailureDetector) Failed(hostname string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	h, ok := d.peers[hostname]
+	if !ok {
+		return true
+	}
+	h.failing = true
+	return h.phi(d.now()) > d.threshold
+}
+
+// Remove forgets the VNode at hostname, after it left or was declared dead.
+func (d *FailureDetector) Remove(hostname string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.peers, hostname)
+}
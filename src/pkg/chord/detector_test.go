@@ -0,0 +1,124 @@
+package chord
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// fakeClock is the time seen by a FailureDetector under test.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// testDetector returns a FailureDetector with the default threshold and a bootstrap interval of a second, reading time from the returned clock.
+func testDetector() (*FailureDetector, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	d := NewFailureDetector(DefaultPhiThreshold, time.Second)
+	d.now = func() time.Time { return clock.now }
+	return d, clock
+}
+
+// beat sends n heartbeats from hostname, interval apart.
+func beat(d *FailureDetector, clock *fakeClock, hostname string, n int, interval time.Duration) {
+	for i := 0; i < n; i++ {
+		clock.advance(interval)
+		d.Heartbeat(hostname)
+	}
+}
+
+func TestPhiGrowsPastThreshold(t *testing.T) {
+	d, clock := testDetector()
+	beat(d, clock, "a", 20, time.Second)
+
+	if phi := d.Phi("a"); phi != 0 {
+		t.Errorf("Phi() of a responding VNode = %g, want 0", phi)
+	}
+
+	clock.advance(time.Second)
+	if d.Failed("a") {
+		t.Fatal("VNode declared dead after a failure at its usual interval")
+	}
+	last := d.Phi("a")
+	for i := 0; i < 10; i++ {
+		clock.advance(100 * time.Millisecond)
+		phi := d.Phi("a")
+		if phi <= last {
+			t.Errorf("Phi() = %g after %d ms more silence, want more than %g", phi, 100*(i+1), last)
+		}
+		last = phi
+	}
+	if !d.Suspect("a") || last <= DefaultPhiThreshold {
+		t.Errorf("Phi() = %g after 2 intervals of silence, want above %d", last, DefaultPhiThreshold)
+	}
+	if !d.Failed("a") {
+		t.Error("Failed() = false for a suspected VNode")
+	}
+}
+
+func TestRegularHeartbeatsStayBelowThreshold(t *testing.T) {
+	d, clock := testDetector()
+	intervals := []time.Duration{900 * time.Millisecond, 1100 * time.Millisecond, time.Second, 1050 * time.Millisecond}
+	d.Heartbeat("a")
+	for i := 0; i < 200; i++ {
+		clock.advance(intervals[i%len(intervals)])
+		// The contact fails once just before the heartbeat arrives.
+		if d.Failed("a") {
+			t.Fatalf("heartbeat %d: VNode declared dead with phi %g", i, d.Phi("a"))
+		}
+		d.Heartbeat("a")
+		if d.Suspect("a") {
+			t.Fatalf("heartbeat %d: VNode suspected after responding", i)
+		}
+	}
+}
+
+func TestHeartbeatSamplesWrap(t *testing.T) {
+	d, clock := testDetector()
+	beat(d, clock, "a", 60, 10*time.Second)
+	beat(d, clock, "a", maxHeartbeatSamples, time.Second)
+
+	h := d.peers["a"]
+	if len(h.intervals) != maxHeartbeatSamples {
+		t.Fatalf("%d intervals kept, want %d", len(h.intervals), maxHeartbeatSamples)
+	}
+	if want := float64(maxHeartbeatSamples); math.Abs(h.sum-want) > 1e-6 || math.Abs(h.sumSq-want) > 1e-6 {
+		t.Errorf("sum %g and sum of squares %g, want %g for the last intervals only", h.sum, h.sumSq, want)
+	}
+	for i, interval := range h.intervals {
+		if interval != 1 {
+			t.Fatalf("interval %d = %g, want the older intervals overwritten", i, interval)
+		}
+	}
+
+	// The long intervals of the past no longer excuse the silence.
+	clock.advance(2 * time.Second)
+	if !d.Failed("a") {
+		t.Errorf("VNode with intervals of a second not declared dead after 2s, phi %g", d.Phi("a"))
+	}
+}
+
+func TestFailedNeverHeardFrom(t *testing.T) {
+	d, clock := testDetector()
+	if phi := d.Phi("a"); phi != 0 {
+		t.Errorf("Phi() of an unknown VNode = %g, want 0", phi)
+	}
+	if !d.Failed("a") {
+		t.Error("Failed() = false for a VNode never heard from")
+	}
+
+	d.Heartbeat("a")
+	clock.advance(100 * time.Millisecond)
+	if d.Failed("a") {
+		t.Error("Failed() = true right after the first heartbeat")
+	}
+
+	d.Remove("a")
+	if !d.Failed("a") {
+		t.Error("Failed() = false for a removed VNode")
+	}
+}
@@ -0,0 +1,177 @@
+package chord
+
+// Certificate bound VNode identities.
+// With identities enabled, the ID of a VNode is the hash of its certificate's
+// public key and its index among the VNodes holding the key rather than of
+// its hostname, so a peer cannot choose its position on the ring. The index
+// is bounded by MaxIdentityVNodes, leaving a certificate holder a handful of
+// positions to pick from. Every VNode reference sent over RPC
+// carries the VNode's Identity, which receivers verify against the trust root
+// before the VNode can become a predecessor, successor or finger.
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+
+	Hash "github.com/arush15june/chord-golang/src/pkg/hash"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// identityPrefix separates identity signatures from other uses of the key.
+const identityPrefix = "chord-identity:"
+
+// MaxIdentityVNodes bounds the VNodes sharing an identity certificate.
+const MaxIdentityVNodes = 64
+
+// maxVerifiedIdentities bounds the cache of verified identities.
+const maxVerifiedIdentities = 4096
+
+// ErrIdentity is returned for VNode references without a valid identity.
+var ErrIdentity = errors.New("chord: invalid VNode identity")
+
+// IdentityID returns the ID of the index'th VNode holding the key of cert.
+func IdentityID(cert *x509.Certificate, index int) uint64 {
+	return Hash.Sum(append(append([]byte{}, cert.RawSubjectPublicKeyInfo...), byte(index)))
+}
+
+// identityMessage returns the message signed by the identity of the index'th VNode at hostname.
+func identityMessage(hostname string, index int) []byte {
+	return []byte(fmt.Sprintf("%s%d:%s", identityPrefix, index, hostname))
+}
+
+// identities signs the identities of local VNodes and verifies the identities of remote ones.
+type identities struct {
+	cert   tls.Certificate
+	leaf   *x509.Certificate
+	signer crypto.Signer
+	// roots verify the certificates of remote VNodes, the system roots are used if nil.
+	roots *x509.CertPool
+
+	mu sync.Mutex
+	// verified maps verified identities to their IDs.
+	verified map[string]uint64
+}
+
+func newIdentities(cert tls.Certificate, roots *x509.CertPool) (*identities, error) {
+	if len(cert.Certificate) == 0 {
+		return nil, errors.New("chord: identity certificate is empty")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("chord: parsing identity certificate: %v", err)
+	}
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("chord: identity key cannot sign")
+	}
+	if _, err := signatureAlgorithm(leaf); err != nil {
+		return nil, err
+	}
+
+	return &identities{
+		cert:     cert,
+		leaf:     leaf,
+		signer:   signer,
+		roots:    roots,
+		verified: make(map[string]uint64),
+	}, nil
+}
+
+// signatureAlgorithm returns the algorithm identities of cert's key are signed with.
+func signatureAlgorithm(cert *x509.Certificate) (x509.SignatureAlgorithm, error) {
+	switch cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return x509.ECDSAWithSHA256, nil
+	case *rsa.PublicKey:
+		return x509.SHA256WithRSA, nil
+	}
+	return x509.UnknownSignatureAlgorithm, fmt.Errorf("chord: unsupported identity key %T", cert.PublicKey)
+}
+
+// sign returns the identity of the index'th local VNode at hostname and its ID.
+func (ids *identities) sign(hostname string, index int) (VNode.Identity, uint64, error) {
+	if index < 0 || index >= MaxIdentityVNodes {
+		return VNode.Identity{}, 0, fmt.Errorf("chord: identities are limited to %d VNodes", MaxIdentityVNodes)
+	}
+	digest := sha256.Sum256(identityMessage(hostname, index))
+	signature, err := ids.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return VNode.Identity{}, 0, err
+	}
+
+	identity := VNode.Identity{
+		Hostname:    hostname,
+		Index:       index,
+		Certificate: ids.cert.Certificate[0],
+		Signature:   signature,
+	}
+	return identity, IdentityID(ids.leaf, index), nil
+}
+
+// verify checks the certificate of identity against the roots and its signature,
+// and returns the ID of the VNode.
+func (ids *identities) verify(identity VNode.Identity) (uint64, error) {
+	if len(identity.Signature) == 0 {
+		return 0, fmt.Errorf("%v: no identity presented", ErrIdentity)
+	}
+	if identity.Index < 0 || identity.Index >= MaxIdentityVNodes {
+		return 0, fmt.Errorf("%v: %s: index %d out of range", ErrIdentity, identity.Hostname, identity.Index)
+	}
+
+	// The key covers every field, a signature is only valid with its hostname, index and certificate.
+	cacheKey := fmt.Sprintf("%d:%s%d:%d:%s%s", len(identity.Hostname), identity.Hostname, identity.Index, len(identity.Certificate), identity.Certificate, identity.Signature)
+	ids.mu.Lock()
+	id, ok := ids.verified[cacheKey]
+	ids.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	cert, err := x509.ParseCertificate(identity.Certificate)
+	if err != nil {
+		return 0, fmt.Errorf("%v: %s: %v", ErrIdentity, identity.Hostname, err)
+	}
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     ids.roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%v: %s: %v", ErrIdentity, identity.Hostname, err)
+	}
+	algorithm, err := signatureAlgorithm(cert)
+	if err != nil {
+		return 0, fmt.Errorf("%v: %s: %v", ErrIdentity, identity.Hostname, err)
+	}
+	if err := cert.CheckSignature(algorithm, identityMessage(identity.Hostname, identity.Index), identity.Signature); err != nil {
+		return 0, fmt.Errorf("%v: %s: %v", ErrIdentity, identity.Hostname, err)
+	}
+
+	id = IdentityID(cert, identity.Index)
+	ids.mu.Lock()
+	if len(ids.verified) >= maxVerifiedIdentities {
+		ids.verified = make(map[string]uint64)
+	}
+	ids.verified[cacheKey] = id
+	ids.mu.Unlock()
+
+	return id, nil
+}
+
+// identityOf returns the identity of vnode, the zero Identity if it has none.
+func identityOf(vnode VNode.VNodeProtocol) VNode.Identity {
+	switch v := vnode.(type) {
+	case *LocalVNode:
+		return v.identity
+	case *RemoteVNode:
+		return v.identity
+	}
+	return VNode.Identity{}
+}
@@ -0,0 +1,70 @@
+package chord
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestIdentityIDIgnoresHostname(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	ids, err := newIdentities(newTestCert(t, "node", ca).tlsCert(), roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, id, err := ids.sign("127.0.0.1:8000", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified, err := ids.verify(identity); err != nil || verified != id {
+		t.Fatalf("verify() = %d, %v, want %d", verified, err, id)
+	}
+
+	moved, movedID, err := ids.sign("127.0.0.1:9000", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movedID != id {
+		t.Errorf("ID changed with the hostname: %d, want %d", movedID, id)
+	}
+	if _, err := ids.verify(moved); err != nil {
+		t.Errorf("verify() of moved identity: %v", err)
+	}
+	if _, otherID, _ := ids.sign("127.0.0.1:8000", 4); otherID == id {
+		t.Errorf("indexes 3 and 4 share ID %d", id)
+	}
+}
+
+func TestIdentityRejectsForgedIndexes(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	ids, err := newIdentities(newTestCert(t, "node", ca).tlsCert(), roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := ids.sign("127.0.0.1:8000", MaxIdentityVNodes); err == nil {
+		t.Errorf("sign() accepted index %d", MaxIdentityVNodes)
+	}
+
+	identity, _, err := ids.sign("127.0.0.1:8000", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []int{1, -1, MaxIdentityVNodes} {
+		forged := identity
+		forged.Index = index
+		if _, err := ids.verify(forged); err == nil {
+			t.Errorf("verify() accepted identity signed for index 0 claiming %d", index)
+		}
+	}
+
+	forged := identity
+	forged.Hostname = "127.0.0.1:8001"
+	if _, err := ids.verify(forged); err == nil {
+		t.Error("verify() accepted identity signed for another hostname")
+	}
+}
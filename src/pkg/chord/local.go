@@ -0,0 +1,836 @@
+package chord
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	Hash "github.com/arush15june/chord-golang/src/pkg/hash"
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+	Util "github.com/arush15june/chord-golang/src/pkg/util"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// LocalVNode is a VNode communicating using direct method calls rather than RPC.
+type LocalVNode struct {
+	VNode.VNode
+
+	// mu guards the successor, predecessor and finger tables.
+	// It is never held while calling another VNode.
+	mu sync.RWMutex
+
+	// ring is the Ring running the VNode.
+	ring *Ring
+
+	id uint64
+	// index is the position of the VNode among the VNodes of its ring.
+	index int
+	// identity is the signed identity of the VNode, if the ring uses identities.
+	identity VNode.Identity
+
+	successors    []VNode.VNodeProtocol
+	predecessor   VNode.VNodeProtocol
+	maxSuccessors int
+
+	fingers    []VNode.VNodeProtocol
+	maxFingers int
+
+	// stabilizeSchedule and fixFingerSchedule adapt the intervals of stabilization
+	// and finger fixing to the changes of the routing state.
+	stabilizeSchedule *schedule
+	fixFingerSchedule *schedule
+	checkPredInterval time.Duration
+	verifyInterval    time.Duration
+
+	stopStabilizeChan chan bool
+	stopFixFingerChan chan bool
+	stopCheckPredChan chan bool
+	stopVerifyChan    chan bool
+	stopOnce          sync.Once
+
+	// load counts the lookup and routing requests handled by the VNode.
+	load uint64
+	// shed counts the RPCs to the VNode refused by admission control.
+	shed uint64
+
+	// violations counts the invariant violations found by the VNode.
+	violations uint64
+	// verifiedFinger is the index of the finger verified last.
+	verifiedFinger uint64
+	// disconnected counts the consecutive checks the seeds did not route to the VNode.
+	disconnected int32
+
+	log *Logging.Logger
+}
+
+// newLocalVNode initializes the index'th local vnode of ring from the ring options.
+func newLocalVNode(Hostname string, index int, ring *Ring) *LocalVNode {
+	vnode := &LocalVNode{
+		VNode:             VNode.VNode{Hostname: Hostname},
+		ring:              ring,
+		index:             index,
+		id:                Hash.Sum([]byte(Hostname)),
+		stabilizeSchedule: newSchedule(ring.options.MinStabilizeInterval, ring.options.MaxStabilizeInterval),
+		fixFingerSchedule: newSchedule(ring.options.FixFingerInterval, ring.options.MaxFixFingerInterval),
+		checkPredInterval: ring.options.CheckPredInterval,
+		verifyInterval:    ring.options.VerifyInterval,
+		maxSuccessors:     ring.options.MaxSuccessors,
+		maxFingers:        ring.options.MaxFingers,
+	}
+
+	vnode.initStopChannels()
+	vnode.initLists()
+	vnode.initLogger()
+	return vnode
+}
+
+// StopVNode stops the VNode background operations.
+// It is safe to call more than once and before the worker is started.
+func (node *LocalVNode) StopVNode() {
+	node.stopOnce.Do(func() {
+		close(node.stopStabilizeChan)
+		close(node.stopFixFingerChan)
+		close(node.stopCheckPredChan)
+		close(node.stopVerifyChan)
+	})
+}
+
+// SetHostname sets a new hostname for the id and recomputes the ID.
+// With identities, the ID is derived from the signed identity of the VNode's index.
+func (node *LocalVNode) SetHostname(newHostname string) error {
+	node.log.Debug("Changing Hostname", "new_hostname", newHostname)
+
+	id := Hash.Sum([]byte(newHostname))
+	var identity VNode.Identity
+	if ids := node.ring.transport.identities; ids != nil {
+		var err error
+		identity, id, err = ids.sign(newHostname, node.index)
+		if err != nil {
+			return err
+		}
+	}
+
+	node.VNode.Hostname = newHostname
+	node.id = id
+	node.identity = identity
+	node.initLogger()
+	return nil
+}
+
+// initLogger attaches the ID and hostname of the VNode to its logger.
+func (node *LocalVNode) initLogger() {
+	node.log = node.ring.options.Logs.Logger("vnode").With("vnode", node.ID(), "hostname", node.Hostname())
+}
+
+// initStopChannels initializes the channels used to stop backgorund goroutines.
+func (node *LocalVNode) initStopChannels() {
+	node.stopStabilizeChan = make(chan bool)
+	node.stopFixFingerChan = make(chan bool)
+	node.stopCheckPredChan = make(chan bool)
+	node.stopVerifyChan = make(chan bool)
+}
+
+func (node *LocalVNode) initLists() {
+	node.successors = make([]VNode.VNodeProtocol, node.maxSuccessors)
+	node.fingers = make([]VNode.VNodeProtocol, node.maxFingers)
+}
+
+// successor returns the first entry of the successor table.
+func (node *LocalVNode) successor() VNode.VNodeProtocol {
+	node.mu.RLock()
+	defer node.mu.RUnlock()
+
+	return node.successors[0]
+}
+
+// setSuccessor replaces the first entry of the successor table.
+func (node *LocalVNode) setSuccessor(successor VNode.VNodeProtocol) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.successors[0] = successor
+}
+
+// successorList returns a copy of the known successors, closest first.
+func (node *LocalVNode) successorList() []VNode.VNodeProtocol {
+	node.mu.RLock()
+	defer node.mu.RUnlock()
+
+	successors := make([]VNode.VNodeProtocol, 0, len(node.successors))
+	for _, successor := range node.successors {
+		if successor == nil {
+			break
+		}
+		successors = append(successors, successor)
+	}
+	return successors
+}
+
+// currentPredecessor returns the predecessor, nil if it is unknown.
+func (node *LocalVNode) currentPredecessor() VNode.VNodeProtocol {
+	node.mu.RLock()
+	defer node.mu.RUnlock()
+
+	return node.predecessor
+}
+
+// setPredecessor replaces the predecessor.
+func (node *LocalVNode) setPredecessor(predecessor VNode.VNodeProtocol) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.predecessor = predecessor
+}
+
+// fingerTable returns a copy of the finger table.
+func (node *LocalVNode) fingerTable() []VNode.VNodeProtocol {
+	node.mu.RLock()
+	defer node.mu.RUnlock()
+
+	fingers := make([]VNode.VNodeProtocol, len(node.fingers))
+	copy(fingers, node.fingers)
+	return fingers
+}
+
+// Hostname returns the hostname of the LocalVNode.
+func (node *LocalVNode) Hostname() string {
+	return node.VNode.Hostname
+}
+
+// ID returns the ID of the LocalVNode.
+func (node *LocalVNode) ID() uint64 {
+	return node.id
+}
+
+// Stabilize executes after certain time intervals to fix successors.
+// > Each time node n runs Stabilize(), it asks its successor
+// > for the successor’s predecessor p, and decides whether p
+// > should be n’s successor instead. stabilize() notifies node
+// > n’s successor of n’s existence, giving the successor the chance
+// > to change its predecessor to n.
+func (node *LocalVNode) Stabilize() error {
+	_, err := node.stabilize()
+	return err
+}
+
+// stabilize runs Stabilize and reports whether the successors changed.
+func (node *LocalVNode) stabilize() (bool, error) {
+	node.log.Debug("Stabilizing VNode")
+
+	changed := false
+	successor := node.successor()
+	if successor.ID() != node.ID() {
+		if err := successor.Ping(); err != nil {
+			if !node.failed(successor, err) {
+				node.log.Debug("Successor suspected", "successor", successor.Hostname(), "phi", node.detector().Phi(successor.Hostname()), "err", err)
+				return false, err
+			}
+			node.log.Warn("Successor dead", "successor", successor.Hostname(), "err", err)
+			node.detector().Remove(successor.Hostname())
+			successor = node.replaceSuccessor(successor)
+			changed = true
+		} else {
+			node.detector().Heartbeat(successor.Hostname())
+		}
+	}
+
+	verifySuccesorNode, _ := successor.GetPredecessor()
+	if verifySuccesorNode != nil && verifySuccesorNode.IsBetweenNodes(node, successor) {
+		if err := node.confirm(verifySuccesorNode); err != nil {
+			node.log.Warn("Ignoring successor claim", "successor", successor.Hostname(), "claimed", verifySuccesorNode.Hostname(), "err", err)
+			verifySuccesorNode = nil
+		}
+	}
+	if verifySuccesorNode != nil && verifySuccesorNode.IsBetweenNodes(node, successor) {
+		previous := successor
+		successor = verifySuccesorNode
+		node.setSuccessor(successor)
+		node.log.Info("Updated successor", "successor", successor.Hostname(), "successor_id", successor.ID())
+		node.delegate().NewSuccessor(node, previous, successor)
+		changed = true
+	}
+
+	if node.updateSuccessorList(successor) {
+		changed = true
+	}
+
+	if successor.ID() != node.ID() {
+		err := successor.Notify(node)
+		node.log.Debug("Notified successor of VNode", "successor", successor.Hostname(), "err", err)
+		return changed, err
+	}
+
+	return changed, nil
+}
+
+// replaceSuccessor replaces a failed successor by the next live VNode of the
+// successor list or the finger table, or by the VNode itself if none is alive.
+// Stabilization walks the replacement back to the closest live successor.
+func (node *LocalVNode) replaceSuccessor(failed VNode.VNodeProtocol) VNode.VNodeProtocol {
+	candidates := append(node.successorList(), node.fingerTable()...)
+
+	var replacement VNode.VNodeProtocol = node
+	for _, candidate := range candidates {
+		if candidate == nil || candidate.ID() == failed.ID() || candidate.ID() == node.ID() || node.detector().Suspect(candidate.Hostname()) {
+			continue
+		}
+		if err := candidate.Ping(); err == nil || err == ErrOverloaded {
+			node.detector().Heartbeat(candidate.Hostname())
+			replacement = candidate
+			break
+		}
+	}
+
+	node.mu.Lock()
+	replaced := node.successors[0] != nil && node.successors[0].ID() == failed.ID()
+	if replaced {
+		node.successors[0] = replacement
+		for i := 1; i < len(node.successors); i++ {
+			node.successors[i] = nil
+		}
+	}
+	node.mu.Unlock()
+
+	if !replaced {
+		return node.successor()
+	}
+
+	node.log.Info("Replaced dead successor", "failed", failed.Hostname(), "successor", replacement.Hostname(), "successor_id", replacement.ID())
+	node.delegate().NewSuccessor(node, failed, replacement)
+	return replacement
+}
+
+// updateSuccessorList refills the successor list behind successor with the
+// successors of successor, up to the VNode itself.
+// It reports whether the list changed.
+func (node *LocalVNode) updateSuccessorList(successor VNode.VNodeProtocol) bool {
+	var successors []VNode.VNodeProtocol
+	if node.maxSuccessors > 1 && successor.ID() != node.ID() {
+		var err error
+		successors, err = successor.FindSuccessors(node.maxSuccessors - 1)
+		if err != nil {
+			node.log.Debug("Failed to fetch successor list", "successor", successor.Hostname(), "err", err)
+			return false
+		}
+	}
+	for i, next := range successors {
+		if next.ID() == node.ID() {
+			break
+		}
+		if err := node.confirm(next); err != nil {
+			node.log.Debug("Truncating successor list", "successor", successor.Hostname(), "claimed", next.Hostname(), "err", err)
+			successors = successors[:i]
+			break
+		}
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	if node.successors[0] == nil || node.successors[0].ID() != successor.ID() {
+		return false
+	}
+
+	changed := false
+	i := 1
+	for _, next := range successors {
+		if i >= len(node.successors) || next.ID() == node.ID() {
+			break
+		}
+		if node.successors[i] == nil || node.successors[i].ID() != next.ID() {
+			changed = true
+		}
+		node.successors[i] = next
+		i++
+	}
+	for ; i < len(node.successors); i++ {
+		if node.successors[i] != nil {
+			changed = true
+		}
+		node.successors[i] = nil
+	}
+	return changed
+}
+
+// StabilizeRoutine runs Stabilize() periodically. The interval starts at
+// minStabilizeInterval, backs off up to maxStabilizeInterval while the
+// successors do not change and is reset by any change.
+func (node *LocalVNode) StabilizeRoutine() error {
+	for {
+		changed, err := node.stabilize()
+		if changed {
+			// Fingers pointing into the changed range are stale.
+			node.fixFingerSchedule.reset()
+		}
+
+		// Overloaded successors are backed off from rather than hurried.
+		interval := node.stabilizeSchedule.next(changed || (err != nil && err != ErrOverloaded))
+		node.log.Debug("Scheduled stabilization", "interval", interval, "changed", changed)
+		if !node.stabilizeSchedule.wait(interval, node.stopStabilizeChan) {
+			return nil
+		}
+	}
+}
+
+// FixFinger updates the finger tables. fingerNumber is the n'th finger not the finger list index.
+func (node *LocalVNode) FixFinger(fingerNumber int) error {
+	_, err := node.fixFinger(fingerNumber)
+	return err
+}
+
+// fixFinger runs FixFinger and reports whether the finger changed.
+func (node *LocalVNode) fixFinger(fingerNumber int) (bool, error) {
+	node.log.Debug("Fixing Finger", "finger", fingerNumber)
+	if fingerNumber < 1 {
+		return false, errors.New("invalid finger number")
+	}
+	if fingerNumber > node.maxFingers {
+		return false, errors.New("finger number out of bounds")
+	}
+
+	fingerIndex := fingerNumber - 1
+	fingerID := node.ID() + uint64(math.Exp2(float64(fingerNumber-1)))
+
+	finger, err := node.FindSuccessor(fingerID)
+	if err == nil {
+		if err = node.confirmSuccessor(fingerID, finger); err != nil {
+			node.log.Debug("Ignoring finger claim", "finger", fingerNumber, "claimed", finger.Hostname(), "err", err)
+			finger = nil
+		}
+	}
+
+	node.mu.Lock()
+	changed := hostnameOf(node.fingers[fingerIndex]) != hostnameOf(finger)
+	node.fingers[fingerIndex] = finger
+	node.mu.Unlock()
+
+	if finger != nil {
+		node.log.Debug("Fixed Finger", "finger", fingerNumber, "finger_id", fingerID, "finger_hostname", finger.Hostname())
+	}
+
+	return changed, err
+}
+
+// FixFingersRoutine periodically fixes the finger indices, one per interval.
+// The interval backs off while fingers do not change and is reset by any change.
+func (node *LocalVNode) FixFingersRoutine() error {
+	fingerNumber := 1
+
+	for {
+		changed, err := node.fixFinger(fingerNumber)
+
+		if !node.fixFingerSchedule.wait(node.fixFingerSchedule.next(changed || (err != nil && err != ErrOverloaded)), node.stopFixFingerChan) {
+			return nil
+		}
+
+		if err != nil || fingerNumber >= node.maxFingers {
+			// Reset fixing process on failure.
+			fingerNumber = 1
+		} else {
+			fingerNumber++
+		}
+	}
+}
+
+// Ping returns nil as LocalVNode will always be alive.
+func (node *LocalVNode) Ping() error {
+	node.log.Debug("Received request for Ping")
+	return nil
+}
+
+// FindSuccessors returns up to n successors of the VNode, closest first.
+func (node *LocalVNode) FindSuccessors(n int) ([]VNode.VNodeProtocol, error) {
+	successors := node.successorList()
+	if n < len(successors) {
+		successors = successors[:n]
+	}
+
+	return successors, nil
+}
+
+// FindSuccessor finds the successor for the key id recursively.
+func (node *LocalVNode) FindSuccessor(id uint64) (VNode.VNodeProtocol, error) {
+	node.log.Debug("Finding Successor", "id", id)
+	atomic.AddUint64(&node.load, 1)
+
+	successor := node.successor()
+	if Util.IsBetweenID(id, node.ID(), successor.ID()) {
+		node.log.Debug("ID lies between VNode and successor", "id", id, "successor", successor.Hostname(), "successor_id", successor.ID())
+		return successor, nil
+	}
+
+	node.log.Debug("ID not in successor, finding closest predecessor", "id", id)
+	var overloaded map[uint64]bool
+	for {
+		closestNode := node.closestPrecedingNode(id, overloaded)
+		if closestNode.ID() == node.ID() {
+			if overloaded != nil {
+				return nil, ErrOverloaded
+			}
+			return node, nil
+		}
+
+		successor, err := closestNode.FindSuccessor(id)
+		if err == nil {
+			node.detector().Heartbeat(closestNode.Hostname())
+			return successor, nil
+		}
+		if err == ErrOverloaded {
+			// Route through a farther finger instead.
+			node.log.Debug("Finger overloaded", "finger", closestNode.Hostname())
+			node.detector().Heartbeat(closestNode.Hostname())
+			if overloaded == nil {
+				overloaded = make(map[uint64]bool)
+			}
+			overloaded[closestNode.ID()] = true
+			continue
+		}
+		if !node.detector().Failed(closestNode.Hostname()) {
+			return nil, err
+		}
+
+		// Route around the dead finger.
+		node.log.Warn("Finger dead", "finger", closestNode.Hostname(), "err", err)
+		node.detector().Remove(closestNode.Hostname())
+		node.removeFinger(closestNode)
+	}
+}
+
+// failed records a failed contact with vnode and reports whether the failure
+// detector declares it dead. Overloaded VNodes responded and are alive.
+func (node *LocalVNode) failed(vnode VNode.VNodeProtocol, err error) bool {
+	if err == ErrOverloaded {
+		node.detector().Heartbeat(vnode.Hostname())
+		return false
+	}
+	return node.detector().Failed(vnode.Hostname())
+}
+
+// removeFinger clears every finger pointing to vnode.
+func (node *LocalVNode) removeFinger(vnode VNode.VNodeProtocol) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	for i, finger := range node.fingers {
+		if finger != nil && finger.ID() == vnode.ID() {
+			node.fingers[i] = nil
+		}
+	}
+}
+
+// ClosestPrecedingNode finds the closest preceding node to the ID in the FingerTable.
+// Fingers suspected by the failure detector are skipped.
+func (node *LocalVNode) ClosestPrecedingNode(id uint64) VNode.VNodeProtocol {
+	return node.closestPrecedingNode(id, nil)
+}
+
+// closestPrecedingNode runs ClosestPrecedingNode, also skipping the fingers in skip.
+func (node *LocalVNode) closestPrecedingNode(id uint64, skip map[uint64]bool) VNode.VNodeProtocol {
+	fingers := node.fingerTable()
+	for i := len(fingers) - 1; i >= 0; i-- {
+		finger := fingers[i]
+		if finger != nil && !skip[finger.ID()] && !node.detector().Suspect(finger.Hostname()) {
+			if finger.ID() != id && Util.IsBetweenID(finger.ID(), node.ID(), id) {
+				node.log.Debug("Found closest preceding node", "id", id, "finger", finger.Hostname(), "finger_id", finger.ID())
+				return finger
+			}
+		}
+	}
+
+	return node
+}
+
+// Notify verifies the notifying node to be its predecessor and updates itself.
+func (node *LocalVNode) Notify(notifyingNode VNode.VNodeProtocol) error {
+	node.log.Debug("Notification received", "from", notifyingNode.Hostname(), "from_id", notifyingNode.ID())
+	previous := node.currentPredecessor()
+	if previous != nil && !notifyingNode.IsBetweenNodes(previous, node) {
+		if previous.ID() == notifyingNode.ID() && previous.Hostname() == notifyingNode.Hostname() {
+			node.detector().Heartbeat(notifyingNode.Hostname())
+		}
+		return nil
+	}
+	// Notifications are not trusted, the claimed predecessor is confirmed before it is adopted.
+	if err := node.confirm(notifyingNode); err != nil {
+		node.log.Warn("Rejected predecessor claim", "from", notifyingNode.Hostname(), "err", err)
+		return err
+	}
+
+	node.mu.Lock()
+	updated := node.predecessor == previous
+	if updated {
+		node.predecessor = notifyingNode
+	}
+	node.mu.Unlock()
+
+	if updated {
+		node.log.Info("Updated predecessor", "predecessor", notifyingNode.Hostname(), "predecessor_id", notifyingNode.ID())
+		node.stabilizeSchedule.reset()
+		node.delegate().NewPredecessor(node, previous, notifyingNode)
+	}
+
+	return nil
+}
+
+// CheckPredecessor verifies if the nodes predecessor is alive.
+// A predecessor failing to respond is cleared once the failure detector declares it dead.
+func (node *LocalVNode) CheckPredecessor() error {
+	node.log.Debug("Checking liveness of predecessor")
+	predecessor := node.currentPredecessor()
+	if predecessor == nil {
+		node.log.Debug("No predecessor present")
+		return nil
+	}
+
+	err := predecessor.Ping()
+	if err != nil {
+		if !node.failed(predecessor, err) {
+			node.log.Debug("Predecessor suspected", "predecessor", predecessor.Hostname(), "phi", node.detector().Phi(predecessor.Hostname()), "err", err)
+			return err
+		}
+		node.log.Warn("Predecessor dead", "predecessor", predecessor.Hostname(), "err", err)
+		node.detector().Remove(predecessor.Hostname())
+
+		node.mu.Lock()
+		failed := node.predecessor == predecessor
+		if failed {
+			node.predecessor = nil
+		}
+		node.mu.Unlock()
+
+		if failed {
+			node.stabilizeSchedule.reset()
+			node.delegate().PredecessorFailed(node, predecessor)
+		}
+		return fmt.Errorf("predecessor %s dead", predecessor.Hostname())
+	}
+
+	node.detector().Heartbeat(predecessor.Hostname())
+	return nil
+}
+
+// CheckPredecessorRoutine checks the liveness of predecessor periodically.
+func (node *LocalVNode) CheckPredecessorRoutine() error {
+	exit := false
+
+	for {
+		node.CheckPredecessor()
+
+		timer := time.NewTimer(node.checkPredInterval)
+		select {
+		case <-timer.C:
+		case <-node.stopCheckPredChan:
+			exit = true
+		}
+
+		if exit {
+			break
+		}
+	}
+
+	return nil
+}
+
+// GetPredecessor returns the predecessor of the VNode.
+func (node *LocalVNode) GetPredecessor() (VNode.VNodeProtocol, error) {
+	predecessor := node.currentPredecessor()
+	if predecessor == nil {
+		node.log.Debug("VNode has no predecessor")
+		return nil, errors.New("VNode does not have predecessor")
+	}
+
+	node.log.Debug("Returning predecessor", "predecessor", predecessor.Hostname())
+	return predecessor, nil
+}
+
+func (node *LocalVNode) IsBetweenNodes(vlow VNode.VNodeProtocol, vhigh VNode.VNodeProtocol) bool {
+	return Util.IsBetweenID(node.ID(), vlow.ID(), vhigh.ID())
+}
+
+// Join joins an existing ChordVNode.
+func (node *LocalVNode) Join(chordVNode VNode.VNodeProtocol) error {
+	node.log.Info("Joining VNode", "remote", chordVNode.Hostname(), "remote_id", chordVNode.ID())
+
+	node.setPredecessor(nil)
+	successor, err := chordVNode.FindSuccessor(node.ID())
+	if err != nil {
+		return err
+	}
+	if err := node.confirmSuccessor(node.ID(), successor); err != nil {
+		return err
+	}
+	node.setSuccessor(successor)
+	node.log.Info("Found first successor", "successor", successor.Hostname(), "successor_id", successor.ID())
+
+	return successor.Notify(node)
+}
+
+// Create creates a new chord ring.
+func (node *LocalVNode) Create() error {
+	node.log.Info("Creating new Chord ring")
+
+	node.setPredecessor(nil)
+	node.setSuccessor(node)
+	// for i := 0; i < node.maxFingers; i++ {
+	// 	node.fingers[i] = node
+	// }
+
+	return nil
+}
+
+// Lookup finds the successor of ID.
+func (node *LocalVNode) Lookup(Key string) (string, error) {
+	ID := Hash.Sum([]byte(Key))
+
+	node.log.Debug("Lookup request", "key", Key, "id", ID)
+
+	vnode, err := node.FindSuccessor(ID)
+	if err != nil {
+		node.log.Warn("Lookup failed", "key", Key, "id", ID, "err", err)
+		return "", err
+	}
+	node.log.Debug("Lookup resolved", "key", Key, "id", ID, "owner", vnode.Hostname(), "owner_id", vnode.ID())
+
+	return vnode.Hostname(), nil
+}
+
+// Leave gracefully removes the VNode from the ring.
+// It stops the background operations and informs the predecessor and the
+// successor of the departure so they can link to each other directly.
+func (node *LocalVNode) Leave() error {
+	node.log.Info("Leaving Chord ring")
+	node.StopVNode()
+
+	predecessor := node.currentPredecessor()
+	successor := node.successor()
+	node.delegate().Leaving(node, predecessor, successor)
+
+	var err error
+	if successor != nil && successor.ID() != node.ID() {
+		err = successor.NotifyLeave(node, predecessor, successor)
+		node.log.Debug("Notified successor of leave", "successor", successor.Hostname(), "err", err)
+	}
+
+	if predecessor != nil && predecessor.ID() != node.ID() && (successor == nil || predecessor.ID() != successor.ID()) {
+		predErr := predecessor.NotifyLeave(node, predecessor, successor)
+		node.log.Debug("Notified predecessor of leave", "predecessor", predecessor.Hostname(), "err", predErr)
+		if err == nil {
+			err = predErr
+		}
+	}
+
+	if err != nil {
+		node.log.Warn("Leave was not acknowledged", "err", err)
+	}
+
+	node.delegate().Left(node)
+	return err
+}
+
+// NotifyLeave replaces every reference to a leaving VNode, using the
+// leaving VNode's predecessor and successor as replacements.
+func (node *LocalVNode) NotifyLeave(leaving VNode.VNodeProtocol, predecessor VNode.VNodeProtocol, successor VNode.VNodeProtocol) error {
+	node.log.Debug("Leave notification received", "from", leaving.Hostname(), "from_id", leaving.ID())
+	node.detector().Remove(leaving.Hostname())
+
+	node.mu.Lock()
+
+	predecessorLeft := node.predecessor != nil && node.predecessor.ID() == leaving.ID()
+	if predecessorLeft {
+		if predecessor != nil && predecessor.ID() == node.ID() {
+			predecessor = nil
+		}
+		node.predecessor = predecessor
+	}
+
+	successorLeft := node.successors[0] != nil && node.successors[0].ID() == leaving.ID()
+	if successorLeft {
+		if successor == nil {
+			successor = node
+		}
+		node.successors[0] = successor
+	}
+
+	// Close the gap left in the rest of the successor list.
+	remaining := node.successors[1:1]
+	for _, next := range node.successors[1:] {
+		if next != nil && next.ID() != leaving.ID() && next.ID() != node.successors[0].ID() {
+			remaining = append(remaining, next)
+		}
+	}
+	for i := 1 + len(remaining); i < len(node.successors); i++ {
+		node.successors[i] = nil
+	}
+
+	for i, finger := range node.fingers {
+		if finger != nil && finger.ID() == leaving.ID() {
+			node.fingers[i] = nil
+		}
+	}
+
+	node.mu.Unlock()
+
+	if predecessorLeft {
+		node.log.Info("Predecessor left", "left", leaving.Hostname(), "predecessor", hostnameOf(predecessor))
+		node.delegate().NewPredecessor(node, leaving, predecessor)
+	}
+	if successorLeft {
+		node.log.Info("Successor left", "left", leaving.Hostname(), "successor", successor.Hostname())
+		node.delegate().NewSuccessor(node, leaving, successor)
+	}
+
+	return nil
+}
+
+// Info returns the routing state of the VNode.
+func (node *LocalVNode) Info() (*VNode.Info, error) {
+	info := &VNode.Info{
+		ID:          node.ID(),
+		Hostname:    node.Hostname(),
+		Predecessor: hostnameOf(node.currentPredecessor()),
+	}
+
+	for _, successor := range node.successorList() {
+		info.Successors = append(info.Successors, successor.Hostname())
+	}
+	for _, finger := range node.fingerTable() {
+		info.Fingers = append(info.Fingers, hostnameOf(finger))
+	}
+
+	return info, nil
+}
+
+// detector returns the failure detector of the ring running the VNode.
+func (node *LocalVNode) detector() *FailureDetector {
+	return node.ring.detector
+}
+
+// delegate returns the Delegate of the ring running the VNode.
+func (node *LocalVNode) delegate() Delegate {
+	return node.ring.options.Delegate
+}
+
+// hostnameOf returns the hostname of vnode, or an empty string for nil.
+func hostnameOf(vnode VNode.VNodeProtocol) string {
+	if vnode == nil {
+		return ""
+	}
+	return vnode.Hostname()
+}
+
+func (node *LocalVNode) InitializeFingerTables() {
+	node.FixFinger(1)
+}
+
+// StartWorker initializes background operations of the worker.
+func (node *LocalVNode) StartWorker() {
+	go node.StabilizeRoutine()
+	go node.FixFingersRoutine()
+	go node.CheckPredecessorRoutine()
+	if node.verifyInterval > 0 {
+		go node.VerifyRoutine()
+	}
+}
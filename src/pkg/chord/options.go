@@ -0,0 +1,282 @@
+package chord
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"time"
+
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+)
+
+// Options configures a Ring.
+type Options struct {
+	// Hostname is the address the RPC servers of the VNodes listen on.
+	// Use <host>:0 to assign a random port to every VNode.
+	Hostname string
+	// VNodes is the number of virtual nodes run by the Ring.
+	VNodes int
+
+	// MinStabilizeInterval is the stabilization interval while the successors are changing.
+	MinStabilizeInterval time.Duration
+	// MaxStabilizeInterval is the stabilization interval backed off to while they are not.
+	MaxStabilizeInterval time.Duration
+	// FixFingerInterval is the period between fixing two fingers while fingers are changing.
+	FixFingerInterval time.Duration
+	// MaxFixFingerInterval is the period backed off to while they are not.
+	MaxFixFingerInterval time.Duration
+	// CheckPredInterval is the period between predecessor liveness checks.
+	CheckPredInterval time.Duration
+	// PhiThreshold is the suspicion above which the failure detector declares VNodes dead.
+	PhiThreshold float64
+	// VerifyInterval is the period between invariant checks, 0 disables them.
+	VerifyInterval time.Duration
+	// MaxSuccessors is the size of the successor table.
+	MaxSuccessors int
+	// MaxFingers is the size of the finger table.
+	MaxFingers int
+
+	// JoinPolicy controls retrying joins to an existing ring.
+	JoinPolicy JoinPolicy
+	// SeedSource, if set, is asked for additional seeds on every round of join attempts.
+	SeedSource func() []string
+
+	// StateDir, if set, is the directory the routing state of the VNodes is
+	// saved to every PersistInterval and restored from on restart.
+	StateDir        string
+	PersistInterval time.Duration
+
+	// DialTimeout bounds connecting to remote VNodes.
+	DialTimeout time.Duration
+	// CallTimeout bounds waiting for the reply to an RPC, the connection is
+	// closed if the remote VNode does not reply in time.
+	CallTimeout time.Duration
+	// Admission limits the requests and connections served by the RPC servers.
+	Admission AdmissionPolicy
+	// TLS, if set, secures all RPC connections, see LoadTLSConfig.
+	TLS *tls.Config
+	// Identity, if set, derives the IDs of the VNodes from its certificate and
+	// signs them. Remote VNodes must present identities verified by IdentityRoots,
+	// or the system roots if nil.
+	Identity      *tls.Certificate
+	IdentityRoots *x509.CertPool
+
+	// Delegate is notified of ownership changes of the VNodes.
+	Delegate Delegate
+
+	// ViolationHandler, if set, is called with every invariant violation found.
+	ViolationHandler func(Violation)
+
+	// Stats, if set, adds application statistics to the statistics of every VNode.
+	Stats StatsSource
+
+	// Logs is the registry the Ring creates its loggers from.
+	Logs *Logging.Registry
+}
+
+// JoinPolicy controls how VNodes retry joining an existing ring.
+type JoinPolicy struct {
+	// Timeout bounds the time spent joining, across all attempts.
+	Timeout time.Duration
+	// InitialBackoff is the wait after the first round of failed attempts.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponentially growing wait between rounds.
+	MaxBackoff time.Duration
+}
+
+// Option sets a field of Options.
+type Option func(*Options)
+
+// DefaultOptions returns the options used when no Option overrides them.
+// Logs are discarded by default.
+func DefaultOptions() Options {
+	return Options{
+		Hostname: ":0",
+		VNodes:   1,
+
+		MinStabilizeInterval: time.Second,
+		MaxStabilizeInterval: 45 * time.Second,
+		FixFingerInterval:    time.Second,
+		MaxFixFingerInterval: 45 * time.Second,
+		CheckPredInterval:    15 * time.Second,
+		PhiThreshold:         DefaultPhiThreshold,
+		VerifyInterval:       30 * time.Second,
+		MaxSuccessors:        1,
+		MaxFingers:           6,
+
+		JoinPolicy: JoinPolicy{
+			Timeout:        time.Minute,
+			InitialBackoff: time.Second,
+			MaxBackoff:     30 * time.Second,
+		},
+
+		PersistInterval: 30 * time.Second,
+
+		DialTimeout: 5 * time.Second,
+		CallTimeout: 10 * time.Second,
+		Admission:   AdmissionPolicy{MaxConns: 1024},
+
+		Delegate: NopDelegate{},
+
+		Logs: Logging.NewRegistry(ioutil.Discard, Logging.LogfmtFormat, Logging.ErrorLevel),
+	}
+}
+
+// WithHostname sets the address the RPC servers listen on.
+func WithHostname(hostname string) Option {
+	return func(o *Options) {
+		o.Hostname = hostname
+	}
+}
+
+// WithVNodes sets the number of virtual nodes.
+func WithVNodes(n int) Option {
+	return func(o *Options) {
+		o.VNodes = n
+	}
+}
+
+// WithStabilizeInterval sets the bounds of the adaptive stabilization interval.
+func WithStabilizeInterval(min time.Duration, max time.Duration) Option {
+	return func(o *Options) {
+		o.MinStabilizeInterval = min
+		o.MaxStabilizeInterval = max
+	}
+}
+
+// WithFixFingerInterval sets the period between fixing two fingers while fingers are changing.
+func WithFixFingerInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.FixFingerInterval = interval
+	}
+}
+
+// WithMaxFixFingerInterval sets the period between fixing two fingers backed off to while fingers are not changing.
+func WithMaxFixFingerInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.MaxFixFingerInterval = interval
+	}
+}
+
+// WithCheckPredecessorInterval sets the period between predecessor liveness checks.
+func WithCheckPredecessorInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.CheckPredInterval = interval
+	}
+}
+
+// WithPhiThreshold sets the suspicion above which the failure detector declares VNodes dead.
+// Lower thresholds detect failures faster and mistake slow VNodes for dead ones more often.
+func WithPhiThreshold(threshold float64) Option {
+	return func(o *Options) {
+		o.PhiThreshold = threshold
+	}
+}
+
+// WithVerifyInterval sets the period between invariant checks, 0 disables them.
+func WithVerifyInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.VerifyInterval = interval
+	}
+}
+
+// WithViolationHandler sets a function called with every invariant violation found.
+func WithViolationHandler(handler func(Violation)) Option {
+	return func(o *Options) {
+		o.ViolationHandler = handler
+	}
+}
+
+// WithSuccessors sets the size of the successor table.
+func WithSuccessors(n int) Option {
+	return func(o *Options) {
+		o.MaxSuccessors = n
+	}
+}
+
+// WithFingers sets the size of the finger table.
+func WithFingers(n int) Option {
+	return func(o *Options) {
+		o.MaxFingers = n
+	}
+}
+
+// WithJoinPolicy sets how joins to an existing ring are retried.
+func WithJoinPolicy(policy JoinPolicy) Option {
+	return func(o *Options) {
+		o.JoinPolicy = policy
+	}
+}
+
+// WithSeedSource sets a function asked for additional seeds on every round of join attempts.
+func WithSeedSource(source func() []string) Option {
+	return func(o *Options) {
+		o.SeedSource = source
+	}
+}
+
+// WithStateDir sets the directory the routing state is saved to and the period between saves.
+func WithStateDir(dir string, interval time.Duration) Option {
+	return func(o *Options) {
+		o.StateDir = dir
+		o.PersistInterval = interval
+	}
+}
+
+// WithDialTimeout sets the timeout for connecting to remote VNodes.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.DialTimeout = timeout
+	}
+}
+
+// WithCallTimeout sets the timeout for replies to RPCs to remote VNodes.
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.CallTimeout = timeout
+	}
+}
+
+// WithAdmissionPolicy sets the limits on requests and connections served by the RPC servers.
+func WithAdmissionPolicy(policy AdmissionPolicy) Option {
+	return func(o *Options) {
+		o.Admission = policy
+	}
+}
+
+// WithTLS secures the RPC servers and clients with config, see LoadTLSConfig.
+func WithTLS(config *tls.Config) Option {
+	return func(o *Options) {
+		o.TLS = config
+	}
+}
+
+// WithIdentity derives the IDs of the VNodes from cert and requires remote VNodes
+// to present identities with certificates verified by roots, the system roots if nil.
+func WithIdentity(cert tls.Certificate, roots *x509.CertPool) Option {
+	return func(o *Options) {
+		o.Identity = &cert
+		o.IdentityRoots = roots
+	}
+}
+
+// WithDelegate sets the Delegate notified of ownership changes of the VNodes.
+func WithDelegate(delegate Delegate) Option {
+	return func(o *Options) {
+		o.Delegate = delegate
+	}
+}
+
+// WithStatsSource adds application statistics, e.g. the number of keys stored, to the VNode statistics.
+func WithStatsSource(source StatsSource) Option {
+	return func(o *Options) {
+		o.Stats = source
+	}
+}
+
+// WithLogs sets the registry the Ring creates its loggers from.
+func WithLogs(logs *Logging.Registry) Option {
+	return func(o *Options) {
+		o.Logs = logs
+	}
+}
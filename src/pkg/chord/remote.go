@@ -0,0 +1,85 @@
+package chord
+
+import (
+	Hash "github.com/arush15june/chord-golang/src/pkg/hash"
+	RPC "github.com/arush15june/chord-golang/src/pkg/rpc"
+	Util "github.com/arush15june/chord-golang/src/pkg/util"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// RemoteVNode is a VNode of another process, its protocol methods are RPCs.
+type RemoteVNode struct {
+	VNode.VNode
+	rpc RPC.ChordProtocolRPC
+
+	id       uint64
+	identity VNode.Identity
+}
+
+// InitRemoteVNode initializes a VNode reached over RPC through transport.
+func InitRemoteVNode(Hostname string, transport *Transport) *RemoteVNode {
+	rvnode := &RemoteVNode{
+		VNode: VNode.VNode{Hostname: Hostname},
+		rpc:   InitChordTCPRPCClient(Hostname, transport),
+		id:    Hash.Sum([]byte(Hostname)),
+	}
+	return rvnode
+}
+
+func (node *RemoteVNode) FindSuccessors(n int) ([]VNode.VNodeProtocol, error) {
+	return node.rpc.FindSuccessors(n)
+}
+func (node *RemoteVNode) Notify(vnode VNode.VNodeProtocol) error {
+	return node.rpc.Notify(vnode)
+}
+func (node *RemoteVNode) FindSuccessor(id uint64) (VNode.VNodeProtocol, error) {
+	n, err := node.rpc.FindSuccessor(id)
+
+	return n, err
+}
+func (node *RemoteVNode) Ping() error {
+	return node.rpc.Ping()
+}
+
+// Identify pings the remote VNode and returns the ID and hostname it reports.
+func (node *RemoteVNode) Identify() (uint64, string, error) {
+	return node.rpc.Identify()
+}
+func (node *RemoteVNode) CheckPredecessor() error {
+	return nil
+}
+func (node *RemoteVNode) GetPredecessor() (VNode.VNodeProtocol, error) {
+	return node.rpc.GetPredecessor()
+}
+
+func (node *RemoteVNode) NotifyLeave(leaving VNode.VNodeProtocol, predecessor VNode.VNodeProtocol, successor VNode.VNodeProtocol) error {
+	return node.rpc.NotifyLeave(leaving, predecessor, successor)
+}
+
+func (node *RemoteVNode) IsBetweenNodes(vlow VNode.VNodeProtocol, vhigh VNode.VNodeProtocol) bool {
+	return Util.IsBetweenID(node.ID(), vlow.ID(), vhigh.ID())
+}
+
+func (node *RemoteVNode) Hostname() string {
+	return node.VNode.Hostname
+}
+
+func (node *RemoteVNode) ID() uint64 {
+	return node.id
+}
+
+func (node *RemoteVNode) Route(msg *VNode.Message) error {
+	return node.rpc.Route(msg)
+}
+
+func (node *RemoteVNode) Deliver(msg *VNode.Message) error {
+	return node.rpc.Deliver(msg)
+}
+
+func (node *RemoteVNode) Broadcast(msg *VNode.BroadcastMessage) (*VNode.BroadcastReply, error) {
+	return node.rpc.Broadcast(msg)
+}
+
+func (node *RemoteVNode) Info() (*VNode.Info, error) {
+	return node.rpc.Info()
+}
@@ -0,0 +1,140 @@
+package chord
+
+// Key based routing.
+// Applications route opaque messages to the owner of a key, the message is
+// forwarded hop by hop along the finger tables and every hop gives the
+// application the chance to intercept or rewrite it.
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	Hash "github.com/arush15june/chord-golang/src/pkg/hash"
+	Util "github.com/arush15june/chord-golang/src/pkg/util"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// Message is an opaque application payload routed to the owner of a key.
+type Message = VNode.Message
+
+// Application receives the upcalls for messages routed through the ring.
+// It must be registered under the same name on every Ring routing its messages.
+type Application interface {
+	// Forward is called on every VNode before msg is forwarded to next.
+	// The application may modify msg, returning false stops routing it.
+	Forward(local *LocalVNode, msg *Message, next VNode.VNodeProtocol) bool
+
+	// Deliver is called on the VNode owning msg.Key.
+	Deliver(local *LocalVNode, msg *Message)
+}
+
+// Register registers app to handle messages routed for the named application.
+func (ring *Ring) Register(name string, app Application) {
+	ring.appsMu.Lock()
+	defer ring.appsMu.Unlock()
+
+	ring.apps[name] = app
+}
+
+// application returns the Application registered under name.
+func (ring *Ring) application(name string) (Application, error) {
+	ring.appsMu.RLock()
+	defer ring.appsMu.RUnlock()
+
+	app, ok := ring.apps[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown application %q", name)
+	}
+	return app, nil
+}
+
+// Route routes payload for the named application to the owner of Key.
+// It returns once the message has been delivered or dropped by a Forward upcall.
+func (ring *Ring) Route(application string, Key string, payload []byte) error {
+	return ring.RouteID(application, Hash.Sum([]byte(Key)), payload)
+}
+
+// RouteID routes payload for the named application to the owner of id.
+func (ring *Ring) RouteID(application string, id uint64, payload []byte) error {
+	if !ring.Ready() {
+		return ErrNotReady
+	}
+
+	origin := ring.vnodes[0]
+	msg := &Message{
+		Application: application,
+		Key:         id,
+		Payload:     payload,
+		Origin:      origin.Hostname(),
+	}
+
+	return origin.Route(msg)
+}
+
+// nextHop returns the VNode to forward a message for id to, and whether
+// that VNode owns id. It returns nil if the VNode owns id itself.
+func (node *LocalVNode) nextHop(id uint64) (VNode.VNodeProtocol, bool) {
+	successor := node.successor()
+	if successor == nil || successor.ID() == node.ID() {
+		return nil, false
+	}
+
+	predecessor := node.currentPredecessor()
+	if predecessor != nil && Util.IsBetweenID(id, predecessor.ID(), node.ID()) {
+		return nil, false
+	}
+
+	if Util.IsBetweenID(id, node.ID(), successor.ID()) {
+		return successor, true
+	}
+
+	closestNode := node.ClosestPrecedingNode(id)
+	if closestNode.ID() == node.ID() {
+		return successor, false
+	}
+	return closestNode, false
+}
+
+// Route forwards msg towards the owner of msg.Key, calling the Forward
+// upcall before every hop and the Deliver upcall on the owner.
+func (node *LocalVNode) Route(msg *Message) error {
+	atomic.AddUint64(&node.load, 1)
+
+	app, err := node.ring.application(msg.Application)
+	if err != nil {
+		return err
+	}
+
+	next, owner := node.nextHop(msg.Key)
+	if next == nil {
+		node.log.Debug("Delivering routed message", "application", msg.Application, "key", msg.Key, "hops", msg.Hops)
+		app.Deliver(node, msg)
+		return nil
+	}
+
+	if !app.Forward(node, msg, next) {
+		node.log.Debug("Routed message stopped by application", "application", msg.Application, "key", msg.Key, "hops", msg.Hops)
+		return nil
+	}
+
+	msg.Hops++
+	node.log.Debug("Forwarding routed message", "application", msg.Application, "key", msg.Key, "next", next.Hostname(), "owner", owner)
+	if owner {
+		return next.Deliver(msg)
+	}
+	return next.Route(msg)
+}
+
+// Deliver calls the Deliver upcall of msg's application on the VNode.
+func (node *LocalVNode) Deliver(msg *Message) error {
+	atomic.AddUint64(&node.load, 1)
+
+	app, err := node.ring.application(msg.Application)
+	if err != nil {
+		return err
+	}
+
+	node.log.Debug("Delivering routed message", "application", msg.Application, "key", msg.Key, "hops", msg.Hops)
+	app.Deliver(node, msg)
+	return nil
+}
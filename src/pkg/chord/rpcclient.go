@@ -0,0 +1,351 @@
+package chord
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	RPC "github.com/arush15june/chord-golang/src/pkg/rpc"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// ChordTCPRPCClient implements RPC for the Chord protocol using Golang net/rpc.
+// It is safe for concurrent use, calls share a single connection.
+type ChordTCPRPCClient struct {
+	// mu guards client, which is nil until dialed and after the connection shut down.
+	mu        sync.Mutex
+	client    *rpc.Client
+	transport *Transport
+	Hostname  string
+}
+
+// InitChordTCPRPCClient initializes a ChordTCPRPC object ready to create a server or call a client.
+func InitChordTCPRPCClient(HostnameWithPort string, transport *Transport) *ChordTCPRPCClient {
+	rpc := &ChordTCPRPCClient{
+		Hostname:  HostnameWithPort,
+		transport: transport,
+	}
+
+	return rpc
+}
+
+// InitClient creates a connection to a ChordTCPRPC server unless one is open.
+func (rpcInstance *ChordTCPRPCClient) InitClient() error {
+	_, err := rpcInstance.connect()
+	return err
+}
+
+// connect returns the client of the open connection, dialing and pinging
+// the ChordTCPRPC server if there is none.
+func (rpcInstance *ChordTCPRPCClient) connect() (*rpc.Client, error) {
+	rpcInstance.mu.Lock()
+	defer rpcInstance.mu.Unlock()
+
+	if rpcInstance.client != nil {
+		return rpcInstance.client, nil
+	}
+
+	conn, err := rpcInstance.dial()
+	if err != nil {
+		rpcInstance.transport.log.Debug("Failed to dial", "remote", rpcInstance.Hostname, "err", err)
+		return nil, errors.New("client is dead")
+	}
+	client := rpc.NewClient(conn)
+
+	pingErr := invoke(client, rpcInstance.transport.callTimeout, pingRPCName, &RPC.PingRpcArgs{}, &RPC.PingRpcReply{})
+	if pingErr != nil {
+		client.Close()
+		if pingErr == ErrOverloaded {
+			// Overloaded servers may close the connection, redial on the next call.
+			return nil, pingErr
+		}
+		return nil, errors.New("client is dead")
+	}
+
+	rpcInstance.client = client
+	return client, nil
+}
+
+// dial connects to the ChordTCPRPC server, over TLS if the transport is secured.
+func (rpcInstance *ChordTCPRPCClient) dial() (net.Conn, error) {
+	transport := rpcInstance.transport
+	if transport.tls == nil {
+		return net.DialTimeout("tcp", rpcInstance.Hostname, transport.dialTimeout)
+	}
+
+	dialer := &net.Dialer{Timeout: transport.dialTimeout}
+	return tls.DialWithDialer(dialer, "tcp", rpcInstance.Hostname, transport.tls)
+}
+
+// discard closes client and forgets it if it is still the open connection,
+// so that the next call redials the remote node.
+func (rpcInstance *ChordTCPRPCClient) discard(client *rpc.Client) {
+	rpcInstance.mu.Lock()
+	defer rpcInstance.mu.Unlock()
+
+	if rpcInstance.client == client {
+		rpcInstance.client = nil
+	}
+	client.Close()
+}
+
+// call invokes the named RPC on the remote node, connecting first if needed.
+// A shut down or timed out connection is discarded so that the next call
+// redials the remote node. Requests shed by the remote node fail with ErrOverloaded.
+func (rpcInstance *ChordTCPRPCClient) call(serviceMethod string, args interface{}, reply interface{}) error {
+	client, err := rpcInstance.connect()
+	if err != nil {
+		return err
+	}
+
+	err = invoke(client, rpcInstance.transport.callTimeout, serviceMethod, args, reply)
+	if err == rpc.ErrShutdown || err == ErrCallTimeout {
+		rpcInstance.discard(client)
+	}
+	return err
+}
+
+// ErrCallTimeout is returned when a remote VNode does not reply to an RPC in time.
+var ErrCallTimeout = errors.New("chord: call timed out")
+
+// invoke calls the named RPC on client and waits up to timeout for the reply,
+// mapping shed requests to ErrOverloaded.
+func invoke(client *rpc.Client, timeout time.Duration, serviceMethod string, args interface{}, reply interface{}) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case call := <-client.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1)).Done:
+		err = call.Error
+	case <-timer.C:
+		return ErrCallTimeout
+	}
+	if serverErr, ok := err.(rpc.ServerError); ok && string(serverErr) == ErrOverloaded.Error() {
+		return ErrOverloaded
+	}
+
+	return err
+}
+
+// FindSuccessor calls FindSuccessorRPC on a remote node and returns the successor.
+func (rpc *ChordTCPRPCClient) FindSuccessor(ID uint64) (VNode.VNodeProtocol, error) {
+	var err error
+
+	err = rpc.InitClient()
+	if err != nil {
+		return nil, err
+	}
+
+	args := &RPC.FindSuccRpcArgs{ID: ID}
+	reply := &RPC.FindSuccRpcReply{}
+
+	err = rpc.call(findSuccRPCName, args, reply)
+
+	if err != nil {
+		return nil, err
+	}
+	return rpc.transport.remote(reply.Hostname, reply.Identity)
+}
+
+// FindSuccessors calls FindSuccessorsRPC on the remote node and returns its successors.
+func (rpc *ChordTCPRPCClient) FindSuccessors(n int) ([]VNode.VNodeProtocol, error) {
+	var err error
+
+	err = rpc.InitClient()
+	if err != nil {
+		return nil, err
+	}
+
+	args := &RPC.FindSuccsRpcArgs{N: n}
+	reply := &RPC.FindSuccsRpcReply{}
+
+	err = rpc.call(findSuccsRPCName, args, reply)
+
+	if err != nil {
+		return nil, err
+	}
+
+	successors := make([]VNode.VNodeProtocol, len(reply.Hostnames))
+	for i, hostname := range reply.Hostnames {
+		var identity VNode.Identity
+		if i < len(reply.Identities) {
+			identity = reply.Identities[i]
+		}
+		if successors[i], err = rpc.transport.remote(hostname, identity); err != nil {
+			return nil, err
+		}
+	}
+	return successors, nil
+}
+
+// Notify calls NotifyRPC on the remote node and returns the successor node.
+func (rpc *ChordTCPRPCClient) Notify(vnode VNode.VNodeProtocol) error {
+	var err error
+
+	err = rpc.InitClient()
+	if err != nil {
+		return err
+	}
+
+	args := &RPC.NotifyRpcArgs{Hostname: vnode.Hostname(), Identity: identityOf(vnode)}
+	reply := &RPC.NotifyRpcReply{}
+
+	err = rpc.call(notifyRPCName, args, reply)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Ping calls PingRPC on the remote node.
+func (rpc *ChordTCPRPCClient) Ping() error {
+	var err error
+
+	err = rpc.InitClient()
+	if err != nil {
+		return err
+	}
+
+	args := &RPC.PingRpcArgs{}
+	reply := &RPC.PingRpcReply{}
+
+	err = rpc.call(pingRPCName, args, reply)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Identify calls PingRPC on the remote node and returns the ID and hostname it reports.
+func (rpc *ChordTCPRPCClient) Identify() (uint64, string, error) {
+	err := rpc.InitClient()
+	if err != nil {
+		return 0, "", err
+	}
+
+	args := &RPC.PingRpcArgs{}
+	reply := &RPC.PingRpcReply{}
+
+	err = rpc.call(pingRPCName, args, reply)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return reply.ID, reply.Hostname, nil
+}
+
+// GetPredecessor calls GetPredecessorRPC on the remote node and returns the predecessor.
+func (rpc *ChordTCPRPCClient) GetPredecessor() (VNode.VNodeProtocol, error) {
+	var err error
+
+	err = rpc.InitClient()
+	if err != nil {
+		return nil, err
+	}
+
+	args := &RPC.GetPredecessorRpcArgs{}
+	reply := &RPC.GetPredecessorRpcReply{}
+
+	err = rpc.call(getPredRPCName, args, reply)
+	if err != nil {
+		return nil, err
+	}
+
+	return rpc.transport.remote(reply.Hostname, reply.Identity)
+}
+
+// NotifyLeave calls NotifyLeaveRPC on the remote node.
+func (rpc *ChordTCPRPCClient) NotifyLeave(leaving VNode.VNodeProtocol, predecessor VNode.VNodeProtocol, successor VNode.VNodeProtocol) error {
+	var err error
+
+	err = rpc.InitClient()
+	if err != nil {
+		return err
+	}
+
+	args := &RPC.NotifyLeaveRpcArgs{
+		Hostname:            leaving.Hostname(),
+		PredecessorHostname: hostnameOf(predecessor),
+		SuccessorHostname:   hostnameOf(successor),
+
+		Identity:            identityOf(leaving),
+		PredecessorIdentity: identityOf(predecessor),
+		SuccessorIdentity:   identityOf(successor),
+	}
+	reply := &RPC.NotifyLeaveRpcReply{}
+
+	return rpc.call(notifyLeaveRPCName, args, reply)
+}
+
+// Route calls RouteRPC on the remote node.
+func (rpc *ChordTCPRPCClient) Route(msg *VNode.Message) error {
+	var err error
+
+	err = rpc.InitClient()
+	if err != nil {
+		return err
+	}
+
+	args := &RPC.RouteRpcArgs{Message: *msg}
+	reply := &RPC.RouteRpcReply{}
+
+	return rpc.call(routeRPCName, args, reply)
+}
+
+// Deliver calls DeliverRPC on the remote node.
+func (rpc *ChordTCPRPCClient) Deliver(msg *VNode.Message) error {
+	var err error
+
+	err = rpc.InitClient()
+	if err != nil {
+		return err
+	}
+
+	args := &RPC.DeliverRpcArgs{Message: *msg}
+	reply := &RPC.DeliverRpcReply{}
+
+	return rpc.call(deliverRPCName, args, reply)
+}
+
+// Broadcast calls BroadcastRPC on the remote node.
+func (rpc *ChordTCPRPCClient) Broadcast(msg *VNode.BroadcastMessage) (*VNode.BroadcastReply, error) {
+	var err error
+
+	err = rpc.InitClient()
+	if err != nil {
+		return nil, err
+	}
+
+	args := &RPC.BroadcastRpcArgs{Message: *msg}
+	reply := &RPC.BroadcastRpcReply{}
+
+	if err := rpc.call(broadcastRPCName, args, reply); err != nil {
+		return nil, err
+	}
+	return &reply.Reply, nil
+}
+
+// Info calls InfoRPC on the remote node.
+func (rpc *ChordTCPRPCClient) Info() (*VNode.Info, error) {
+	var err error
+
+	err = rpc.InitClient()
+	if err != nil {
+		return nil, err
+	}
+
+	args := &RPC.InfoRpcArgs{}
+	reply := &RPC.InfoRpcReply{}
+
+	if err := rpc.call(infoRPCName, args, reply); err != nil {
+		return nil, err
+	}
+	return &reply.Info, nil
+}
@@ -0,0 +1,369 @@
+package chord
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/gob"
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+	RPC "github.com/arush15june/chord-golang/src/pkg/rpc"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// handshakeTimeout bounds the TLS handshake of incoming connections.
+const handshakeTimeout = 10 * time.Second
+
+const (
+	findSuccRPCName  = "ChordTCPRPCServer.FindSuccessorRPC"
+	findSuccsRPCName = "ChordTCPRPCServer.FindSuccessorsRPC"
+	notifyRPCName    = "ChordTCPRPCServer.NotifyRPC"
+	pingRPCName      = "ChordTCPRPCServer.PingRPC"
+	getPredRPCName   = "ChordTCPRPCServer.GetPredecessorRPC"
+
+	notifyLeaveRPCName = "ChordTCPRPCServer.NotifyLeaveRPC"
+	routeRPCName       = "ChordTCPRPCServer.RouteRPC"
+	deliverRPCName     = "ChordTCPRPCServer.DeliverRPC"
+	broadcastRPCName   = "ChordTCPRPCServer.BroadcastRPC"
+	infoRPCName        = "ChordTCPRPCServer.InfoRPC"
+)
+
+// ChordTCPRPCServer implements RPC for the Chord protocol using Golang net/rpc.
+type ChordTCPRPCServer struct {
+	vnode     VNode.VNodeProtocol
+	transport *Transport
+	Hostname  string
+	log       *Logging.Logger
+
+	server   *rpc.Server
+	listener net.Listener
+	closed   chan struct{}
+
+	connsMu sync.Mutex
+	conns   map[net.Conn]bool
+}
+
+// InitChordTCPRPCServer initializes a ChordTCPRPC object ready to create a server or call a client.
+func InitChordTCPRPCServer(HostnameWithPort string, vnode VNode.VNodeProtocol, transport *Transport) *ChordTCPRPCServer {
+	rpc := &ChordTCPRPCServer{
+		Hostname:  HostnameWithPort,
+		vnode:     vnode,
+		transport: transport,
+		log:       transport.log,
+		closed:    make(chan struct{}),
+		conns:     make(map[net.Conn]bool),
+	}
+
+	return rpc
+}
+
+// InitServer starts Chord Protocol TCP-RPC server on Hostname:Port.
+// Every server has its own rpc.Server so that each VNode serves its own RPCs.
+func InitServer(rpcInstance *ChordTCPRPCServer) error {
+	rpcInstance.server = rpc.NewServer()
+	if err := rpcInstance.server.Register(rpcInstance); err != nil {
+		return err
+	}
+
+	l, e := net.Listen("tcp", rpcInstance.Hostname)
+	if e != nil {
+		rpcInstance.log.Error("Failed to start Listen server", "address", rpcInstance.Hostname, "err", e)
+		return errors.New("failed to start Listen server")
+	}
+
+	if rpcInstance.transport.tls != nil {
+		l = tls.NewListener(l, rpcInstance.transport.tls)
+	}
+
+	// Reset address as acquired by Listener.
+	address := l.Addr().String()
+	rpcInstance.Hostname = address
+	rpcInstance.listener = l
+	rpcInstance.log.Debug("Listening", "address", address)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				select {
+				case <-rpcInstance.closed:
+					return
+				default:
+				}
+				rpcInstance.log.Warn("Failed to accept connection", "address", address, "err", err)
+				continue
+			}
+			go rpcInstance.serveConn(conn)
+		}
+	}()
+
+	return nil
+}
+
+// serveConn serves RPCs on conn and tracks it until it is closed.
+func (rpcInstance *ChordTCPRPCServer) serveConn(conn net.Conn) {
+	rpcInstance.connsMu.Lock()
+	rpcInstance.conns[conn] = true
+	rpcInstance.connsMu.Unlock()
+
+	// Connections beyond the maximum are served only to shed their first request.
+	admission := rpcInstance.transport.admission
+	admitted := admission.Acquire()
+	if admitted {
+		defer admission.Release()
+	} else {
+		rpcInstance.log.Debug("Shedding connection", "address", rpcInstance.Hostname, "remote", conn.RemoteAddr().String())
+	}
+
+	// Reject peers failing the handshake before serving any RPC.
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		tlsConn.SetDeadline(time.Now().Add(handshakeTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			rpcInstance.log.Warn("Rejected connection", "address", rpcInstance.Hostname, "remote", conn.RemoteAddr().String(), "err", err)
+			conn.Close()
+		} else {
+			tlsConn.SetDeadline(time.Time{})
+			rpcInstance.server.ServeCodec(rpcInstance.newCodec(conn, !admitted))
+		}
+	} else {
+		rpcInstance.server.ServeCodec(rpcInstance.newCodec(conn, !admitted))
+	}
+
+	rpcInstance.connsMu.Lock()
+	delete(rpcInstance.conns, conn)
+	rpcInstance.connsMu.Unlock()
+}
+
+// admissionCodec is the gob codec of net/rpc with admission control. Requests
+// refused admission are answered with ErrOverloaded without calling the RPC.
+type admissionCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+
+	server *ChordTCPRPCServer
+	peer   string
+	// shedAll sheds every request and closes the connection after the first.
+	shedAll bool
+
+	// mu serializes responses written by the rpc.Server and by the codec.
+	mu sync.Mutex
+}
+
+func (rpcInstance *ChordTCPRPCServer) newCodec(conn net.Conn, shedAll bool) *admissionCodec {
+	buf := bufio.NewWriter(conn)
+	return &admissionCodec{
+		rwc:     conn,
+		dec:     gob.NewDecoder(conn),
+		enc:     gob.NewEncoder(buf),
+		encBuf:  buf,
+		server:  rpcInstance,
+		peer:    conn.RemoteAddr().String(),
+		shedAll: shedAll,
+	}
+}
+
+// ReadRequestHeader reads the header of the next admitted request.
+func (c *admissionCodec) ReadRequestHeader(r *rpc.Request) error {
+	for {
+		*r = rpc.Request{}
+		if err := c.dec.Decode(r); err != nil {
+			return err
+		}
+		if !c.shedAll && c.server.transport.admission.Allow(c.peer) {
+			return nil
+		}
+
+		if err := c.ReadRequestBody(nil); err != nil {
+			return err
+		}
+		c.server.shed(r.ServiceMethod, c.peer)
+		err := c.WriteResponse(&rpc.Response{ServiceMethod: r.ServiceMethod, Seq: r.Seq, Error: ErrOverloaded.Error()}, struct{}{})
+		if err != nil {
+			return err
+		}
+		if c.shedAll {
+			return io.EOF
+		}
+	}
+}
+
+func (c *admissionCodec) ReadRequestBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+func (c *admissionCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.enc.Encode(r); err != nil {
+		c.rwc.Close()
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		c.rwc.Close()
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *admissionCodec) Close() error {
+	return c.rwc.Close()
+}
+
+// shed counts and logs a request refused admission.
+func (rpcInstance *ChordTCPRPCServer) shed(serviceMethod string, peer string) {
+	if local, ok := rpcInstance.vnode.(*LocalVNode); ok {
+		atomic.AddUint64(&local.shed, 1)
+	}
+	rpcInstance.log.Debug("Shed request", "address", rpcInstance.Hostname, "remote", peer, "method", serviceMethod)
+}
+
+// Close stops accepting new RPC connections and closes the open ones.
+func (rpcInstance *ChordTCPRPCServer) Close() error {
+	if rpcInstance.listener == nil {
+		return nil
+	}
+
+	select {
+	case <-rpcInstance.closed:
+		return nil
+	default:
+		close(rpcInstance.closed)
+	}
+
+	rpcInstance.log.Debug("Closing listener", "address", rpcInstance.Hostname)
+	err := rpcInstance.listener.Close()
+
+	rpcInstance.connsMu.Lock()
+	for conn := range rpcInstance.conns {
+		conn.Close()
+	}
+	rpcInstance.connsMu.Unlock()
+
+	return err
+}
+
+// FindSuccessorRPC implements the method executed the by the RPC server to find successors on local vnode.
+func (rpc *ChordTCPRPCServer) FindSuccessorRPC(args *RPC.FindSuccRpcArgs, reply *RPC.FindSuccRpcReply) error {
+	successor, err := rpc.vnode.FindSuccessor(args.ID)
+	if err != nil {
+		return err
+	}
+
+	reply.Hostname = successor.Hostname()
+	reply.Identity = identityOf(successor)
+
+	return nil
+}
+
+// FindSuccessorsRPC implements the method executed by the RPC server to find the successor list of local vnode.
+func (rpc *ChordTCPRPCServer) FindSuccessorsRPC(args *RPC.FindSuccsRpcArgs, reply *RPC.FindSuccsRpcReply) error {
+	successors, err := rpc.vnode.FindSuccessors(args.N)
+	if err != nil {
+		return err
+	}
+
+	reply.Hostnames = make([]string, len(successors))
+	reply.Identities = make([]VNode.Identity, len(successors))
+	for i, successor := range successors {
+		reply.Hostnames[i] = successor.Hostname()
+		reply.Identities[i] = identityOf(successor)
+	}
+
+	return nil
+}
+
+// NotifyRPC implements the method executed by the RPC server to notify local vnode.
+func (rpc *ChordTCPRPCServer) NotifyRPC(args *RPC.NotifyRpcArgs, reply *RPC.NotifyRpcReply) error {
+	notifying, err := rpc.transport.remote(args.Hostname, args.Identity)
+	if err != nil {
+		return err
+	}
+
+	return rpc.vnode.Notify(notifying)
+}
+
+// PingRPC implements the method executed by the RPC server to ping local vnode.
+func (rpc *ChordTCPRPCServer) PingRPC(args *RPC.PingRpcArgs, reply *RPC.PingRpcReply) error {
+	err := rpc.vnode.Ping()
+	if err != nil {
+		return errors.New("node died")
+	}
+
+	reply.ID = rpc.vnode.ID()
+	reply.Hostname = rpc.vnode.Hostname()
+	return nil
+}
+
+// GetPredecessorRPC implements the method executed by the RPC server to get predecessor of local vnode.
+func (rpc *ChordTCPRPCServer) GetPredecessorRPC(args *RPC.GetPredecessorRpcArgs, reply *RPC.GetPredecessorRpcReply) error {
+	predecessor, err := rpc.vnode.GetPredecessor()
+	if err != nil {
+		return err
+	}
+
+	reply.Hostname = predecessor.Hostname()
+	reply.Identity = identityOf(predecessor)
+
+	return nil
+}
+
+// NotifyLeaveRPC implements the method executed by the RPC server to notify local vnode of a leaving vnode.
+func (rpc *ChordTCPRPCServer) NotifyLeaveRPC(args *RPC.NotifyLeaveRpcArgs, reply *RPC.NotifyLeaveRpcReply) error {
+	leaving, err := rpc.transport.remote(args.Hostname, args.Identity)
+	if err != nil {
+		return err
+	}
+
+	var predecessor, successor VNode.VNodeProtocol
+	if args.PredecessorHostname != "" {
+		if predecessor, err = rpc.transport.remote(args.PredecessorHostname, args.PredecessorIdentity); err != nil {
+			return err
+		}
+	}
+	if args.SuccessorHostname != "" {
+		if successor, err = rpc.transport.remote(args.SuccessorHostname, args.SuccessorIdentity); err != nil {
+			return err
+		}
+	}
+
+	return rpc.vnode.NotifyLeave(leaving, predecessor, successor)
+}
+
+// RouteRPC implements the method executed by the RPC server to route a message through local vnode.
+func (rpc *ChordTCPRPCServer) RouteRPC(args *RPC.RouteRpcArgs, reply *RPC.RouteRpcReply) error {
+	return rpc.vnode.Route(&args.Message)
+}
+
+// DeliverRPC implements the method executed by the RPC server to deliver a message to local vnode.
+func (rpc *ChordTCPRPCServer) DeliverRPC(args *RPC.DeliverRpcArgs, reply *RPC.DeliverRpcReply) error {
+	return rpc.vnode.Deliver(&args.Message)
+}
+
+// BroadcastRPC implements the method executed by the RPC server to broadcast a message from local vnode.
+func (rpc *ChordTCPRPCServer) BroadcastRPC(args *RPC.BroadcastRpcArgs, reply *RPC.BroadcastRpcReply) error {
+	broadcastReply, err := rpc.vnode.Broadcast(&args.Message)
+	if err != nil {
+		return err
+	}
+	reply.Reply = *broadcastReply
+	return nil
+}
+
+// InfoRPC implements the method executed by the RPC server to describe local vnode.
+func (rpc *ChordTCPRPCServer) InfoRPC(args *RPC.InfoRpcArgs, reply *RPC.InfoRpcReply) error {
+	info, err := rpc.vnode.Info()
+	if err != nil {
+		return err
+	}
+	reply.Info = *info
+	return nil
+}
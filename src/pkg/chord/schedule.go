@@ -0,0 +1,96 @@
+package chord
+
+// Adaptive scheduling of the background routines.
+// A routine runs at its minimum interval while the ring is changing and
+// doubles the interval after every run without changes, up to its maximum,
+// so idle rings exchange few messages and rings under churn heal quickly.
+
+import (
+	"sync"
+	"time"
+
+	Util "github.com/arush15june/chord-golang/src/pkg/util"
+)
+
+// schedule is the adaptive interval of a background routine.
+type schedule struct {
+	min time.Duration
+	max time.Duration
+
+	mu      sync.Mutex
+	current time.Duration
+
+	// wake is signalled when the schedule is reset while backed off.
+	wake chan struct{}
+}
+
+func newSchedule(min time.Duration, max time.Duration) *schedule {
+	return &schedule{
+		min:     min,
+		max:     max,
+		current: min,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// next returns the wait before the next run. The interval is reset to the
+// minimum if the last run changed the routing state and doubled otherwise.
+// The wait is jittered down by up to a quarter to spread the VNodes' runs.
+func (s *schedule) next(changed bool) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if changed {
+		s.current = s.min
+	} else if s.current < s.max {
+		s.current *= 2
+		if s.current > s.max {
+			s.current = s.max
+		}
+	}
+
+	low := s.current - s.current/4
+	if low < s.min {
+		low = s.min
+	}
+	return Util.GetRandomDurationBetween(low, s.current)
+}
+
+// reset shortens the interval to the minimum after a change observed outside
+// the routine, waking the routine if it was backed off.
+func (s *schedule) reset() {
+	s.mu.Lock()
+	backedOff := s.current > s.min
+	s.current = s.min
+	s.mu.Unlock()
+
+	if backedOff {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// interval returns the current interval.
+func (s *schedule) interval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.current
+}
+
+// wait blocks for d, until the schedule is reset or stop is closed.
+// It returns false if stop was closed.
+func (s *schedule) wait(d time.Duration, stop <-chan bool) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-s.wake:
+	case <-stop:
+		return false
+	}
+	return true
+}
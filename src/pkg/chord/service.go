@@ -0,0 +1,32 @@
+package chord
+
+// Application RPC services.
+// Applications needing request/response RPCs between VNodes, e.g. storage,
+// register their own net/rpc services on the RPC servers of the VNodes.
+
+// RegisterService registers the receiver returned by service for every local
+// VNode under name on the VNode's RPC server. Its methods follow the net/rpc
+// conventions and are called on remote VNodes with RemoteVNode.Call.
+func (ring *Ring) RegisterService(name string, service func(vnode *LocalVNode) interface{}) error {
+	for i, server := range ring.servers {
+		if err := server.server.RegisterName(name, service(ring.vnodes[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Call invokes the named method of an application service on the remote VNode.
+func (node *RemoteVNode) Call(serviceMethod string, args interface{}, reply interface{}) error {
+	return node.rpc.Call(serviceMethod, args, reply)
+}
+
+// Call calls serviceMethod of an application service on the remote node.
+func (rpc *ChordTCPRPCClient) Call(serviceMethod string, args interface{}, reply interface{}) error {
+	if err := rpc.InitClient(); err != nil {
+		return err
+	}
+
+	return rpc.call(serviceMethod, args, reply)
+}
@@ -0,0 +1,202 @@
+package chord
+
+// Routing state persistence.
+// Every VNode periodically saves its hostname, ID and routing tables to the
+// state directory. A restarted Ring listens on the saved hostnames, so its
+// VNodes keep their IDs, and joins through the saved peers before the seeds.
+// A Ring asked to create a new ring rejoins its old one if a saved peer responds.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// vnodeState is the routing state of a VNode saved to disk.
+type vnodeState struct {
+	Hostname    string    `json:"hostname"`
+	ID          uint64    `json:"id"`
+	Predecessor string    `json:"predecessor,omitempty"`
+	Successors  []string  `json:"successors"`
+	Fingers     []string  `json:"fingers"`
+	SavedAt     time.Time `json:"saved_at"`
+}
+
+// statePath returns the path of the state of the index'th VNode in dir.
+func statePath(dir string, index int) string {
+	return filepath.Join(dir, fmt.Sprintf("vnode-%d.json", index))
+}
+
+// loadState reads the state of the index'th VNode, nil if none was saved.
+func loadState(dir string, index int) (*vnodeState, error) {
+	data, err := ioutil.ReadFile(statePath(dir, index))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &vnodeState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("chord: parsing %s: %v", statePath(dir, index), err)
+	}
+	return state, nil
+}
+
+// saveState atomically replaces the state of the index'th VNode.
+func saveState(dir string, index int, state vnodeState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := statePath(dir, index)
+	if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// state returns the routing state of the VNode.
+func (node *LocalVNode) state() vnodeState {
+	state := vnodeState{
+		Hostname:    node.Hostname(),
+		ID:          node.ID(),
+		Predecessor: hostnameOf(node.currentPredecessor()),
+		SavedAt:     time.Now(),
+	}
+	for _, successor := range node.successorList() {
+		if successor != nil {
+			state.Successors = append(state.Successors, successor.Hostname())
+		}
+	}
+	for _, finger := range node.fingerTable() {
+		if finger != nil {
+			state.Fingers = append(state.Fingers, finger.Hostname())
+		}
+	}
+	return state
+}
+
+// loadStates reads the saved states of the VNodes, missing ones are nil.
+func (ring *Ring) loadStates() error {
+	if ring.options.StateDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(ring.options.StateDir, 0700); err != nil {
+		return err
+	}
+
+	ring.savedStates = make([]*vnodeState, ring.options.VNodes)
+	for i := range ring.savedStates {
+		state, err := loadState(ring.options.StateDir, i)
+		if err != nil {
+			return err
+		}
+		ring.savedStates[i] = state
+	}
+	return nil
+}
+
+// savedHostname returns the hostname the index'th VNode listened on before a restart, if saved.
+func (ring *Ring) savedHostname(index int) string {
+	if index >= len(ring.savedStates) || ring.savedStates[index] == nil {
+		return ""
+	}
+	return ring.savedStates[index].Hostname
+}
+
+// reusable reports whether a VNode configured to listen on hostname may listen on
+// the saved hostname: on the same host, and on the same port unless assigned randomly.
+func reusable(saved string, hostname string) bool {
+	if saved == "" {
+		return false
+	}
+	savedHost, savedPort, err := net.SplitHostPort(saved)
+	if err != nil {
+		return false
+	}
+	host, port, err := net.SplitHostPort(hostname)
+	if err != nil {
+		return false
+	}
+	sameHost := host == savedHost || (host == "" && net.ParseIP(savedHost).IsUnspecified())
+	return sameHost && (port == "0" || port == savedPort)
+}
+
+// knownPeers returns the remote VNodes found in the saved states, successors
+// first, then predecessors and fingers.
+func (ring *Ring) knownPeers() []VNode.VNodeProtocol {
+	seen := make(map[string]bool)
+	hostnames := make([]string, 0)
+	add := func(hostname string) {
+		if hostname != "" && !seen[hostname] && ring.LocalVNode(hostname) == nil {
+			seen[hostname] = true
+			hostnames = append(hostnames, hostname)
+		}
+	}
+
+	for _, state := range ring.savedStates {
+		if state != nil {
+			for _, hostname := range state.Successors {
+				add(hostname)
+			}
+		}
+	}
+	for _, state := range ring.savedStates {
+		if state != nil {
+			add(state.Predecessor)
+			for _, hostname := range state.Fingers {
+				add(hostname)
+			}
+		}
+	}
+	return ring.remotes(hostnames)
+}
+
+// saveStates saves the routing state of every VNode.
+func (ring *Ring) saveStates() {
+	for i, vnode := range ring.vnodes {
+		if err := saveState(ring.options.StateDir, i, vnode.state()); err != nil {
+			ring.log.Warn("Failed to save routing state", "vnode", vnode.Hostname(), "err", err)
+		}
+	}
+}
+
+// startPersisting saves the routing state now and then periodically until stopPersisting.
+func (ring *Ring) startPersisting() {
+	if ring.options.StateDir == "" {
+		return
+	}
+
+	ring.saveStates()
+	go func() {
+		ticker := time.NewTicker(ring.options.PersistInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ring.saveStates()
+			case <-ring.stopPersist:
+				return
+			}
+		}
+	}()
+}
+
+// stopPersisting stops saving the routing state and saves it a last time.
+func (ring *Ring) stopPersisting() {
+	if ring.options.StateDir == "" || !ring.Ready() {
+		return
+	}
+
+	close(ring.stopPersist)
+	ring.saveStates()
+}
@@ -0,0 +1,222 @@
+package chord
+
+// Ring size estimation and aggregate queries.
+// Every VNode estimates the size of the ring from the density of its
+// successor list. Aggregate queries broadcast a request for per VNode
+// statistics and combine count, sum, min and max on the way back.
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"sync/atomic"
+)
+
+// aggregateApplication is the broadcast application answering aggregate queries.
+const aggregateApplication = "chord.aggregate"
+
+// Built-in VNode statistics.
+const (
+	// KeyspaceStat is the fraction of the identifier space owned by the VNode.
+	KeyspaceStat = "keyspace"
+	// LoadStat is the number of lookup and routing requests handled by the VNode.
+	LoadStat = "load"
+	// EstimateStat is the VNode's estimate of the ring size.
+	EstimateStat = "estimate"
+	// ShedStat is the number of RPCs to the VNode refused by admission control.
+	ShedStat = "shed"
+)
+
+// Stats are named statistics of a VNode.
+type Stats map[string]float64
+
+// StatsSource returns application statistics of a local VNode.
+type StatsSource func(vnode *LocalVNode) Stats
+
+// Aggregate summarises a statistic over the VNodes reporting it.
+type Aggregate struct {
+	Count int     `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+// Mean returns the mean of the statistic over the VNodes reporting it.
+func (a Aggregate) Mean() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return a.Sum / float64(a.Count)
+}
+
+// merge combines two aggregates of the same statistic.
+func (a Aggregate) merge(b Aggregate) Aggregate {
+	if a.Count == 0 {
+		return b
+	}
+	if b.Count == 0 {
+		return a
+	}
+	return Aggregate{
+		Count: a.Count + b.Count,
+		Sum:   a.Sum + b.Sum,
+		Min:   math.Min(a.Min, b.Min),
+		Max:   math.Max(a.Max, b.Max),
+	}
+}
+
+// AggregateResult is the result of an aggregate query over the ring.
+type AggregateResult struct {
+	// VNodes counts the VNodes which answered.
+	VNodes int `json:"vnodes"`
+	// Unreachable counts the VNodes the query could not be forwarded to.
+	Unreachable int `json:"unreachable"`
+	// Stats are the aggregated statistics by name.
+	Stats map[string]Aggregate `json:"stats"`
+}
+
+// EstimateSize estimates the number of VNodes in the ring from the identifier
+// space covered by the successor list. It is exact if the successor list wraps
+// around the whole ring.
+func (node *LocalVNode) EstimateSize() float64 {
+	successors := node.successorList()
+	if len(successors) == 0 || successors[0].ID() == node.ID() {
+		return 1
+	}
+	if len(successors) < node.maxSuccessors {
+		return float64(len(successors) + 1)
+	}
+	seen := make(map[uint64]bool, len(successors))
+	for _, successor := range successors {
+		if successor.ID() == node.ID() || seen[successor.ID()] {
+			// The successor list wrapped around the whole ring.
+			return float64(len(seen) + 1)
+		}
+		seen[successor.ID()] = true
+	}
+
+	distance := successors[len(successors)-1].ID() - node.ID()
+	return float64(len(successors)) * math.Exp2(64) / float64(distance)
+}
+
+// Stats returns the built-in statistics of the VNode along with the statistics
+// of the ring's StatsSource.
+func (node *LocalVNode) Stats() Stats {
+	stats := Stats{
+		LoadStat:     float64(atomic.LoadUint64(&node.load)),
+		EstimateStat: node.EstimateSize(),
+
+		ViolationsStat: float64(atomic.LoadUint64(&node.violations)),
+		ShedStat:       float64(atomic.LoadUint64(&node.shed)),
+	}
+
+	if predecessor := node.currentPredecessor(); predecessor != nil {
+		owned := node.ID() - predecessor.ID()
+		if owned == 0 {
+			stats[KeyspaceStat] = 1
+		} else {
+			stats[KeyspaceStat] = float64(owned) / math.Exp2(64)
+		}
+	}
+
+	if source := node.ring.options.Stats; source != nil {
+		for name, value := range source(node) {
+			stats[name] = value
+		}
+	}
+
+	return stats
+}
+
+// EstimateSize returns the mean of the ring size estimates of the local VNodes.
+func (ring *Ring) EstimateSize() float64 {
+	var sum float64
+	for _, vnode := range ring.vnodes {
+		sum += vnode.EstimateSize()
+	}
+
+	return sum / float64(len(ring.vnodes))
+}
+
+// Aggregate queries every VNode of the ring for the named statistics, all
+// statistics if none are named, and returns them aggregated over the ring.
+func (ring *Ring) Aggregate(names ...string) (*AggregateResult, error) {
+	payload, err := encodeGob(names)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := ring.Broadcast(aggregateApplication, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]Aggregate)
+	if err := decodeGob(reply.Payload, &stats); err != nil {
+		return nil, err
+	}
+
+	return &AggregateResult{
+		VNodes:      reply.Acks,
+		Unreachable: reply.Failed,
+		Stats:       stats,
+	}, nil
+}
+
+// aggregator answers aggregate queries with the statistics of the VNodes.
+type aggregator struct{}
+
+func (aggregator) Receive(local *LocalVNode, msg *BroadcastMessage) []byte {
+	var names []string
+	if err := decodeGob(msg.Payload, &names); err != nil {
+		local.log.Warn("Dropping undecodable aggregate query", "origin", msg.Origin, "err", err)
+		return nil
+	}
+
+	stats := local.Stats()
+	if len(names) > 0 {
+		selected := make(Stats)
+		for _, name := range names {
+			if value, ok := stats[name]; ok {
+				selected[name] = value
+			}
+		}
+		stats = selected
+	}
+
+	aggregates := make(map[string]Aggregate, len(stats))
+	for name, value := range stats {
+		aggregates[name] = Aggregate{Count: 1, Sum: value, Min: value, Max: value}
+	}
+
+	payload, _ := encodeGob(aggregates)
+	return payload
+}
+
+func (aggregator) Aggregate(replies [][]byte) []byte {
+	aggregates := make(map[string]Aggregate)
+	for _, reply := range replies {
+		var stats map[string]Aggregate
+		if len(reply) == 0 || decodeGob(reply, &stats) != nil {
+			continue
+		}
+		for name, aggregate := range stats {
+			aggregates[name] = aggregates[name].merge(aggregate)
+		}
+	}
+
+	payload, _ := encodeGob(aggregates)
+	return payload
+}
+
+func encodeGob(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeGob(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
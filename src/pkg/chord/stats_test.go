@@ -0,0 +1,98 @@
+package chord
+
+import (
+	"math"
+	"testing"
+
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// vnodeWithSuccessors returns a VNode with ID id and the successor list of the given IDs.
+func vnodeWithSuccessors(id uint64, maxSuccessors int, successors ...uint64) *LocalVNode {
+	node := &LocalVNode{id: id, maxSuccessors: maxSuccessors}
+	node.successors = make([]VNode.VNodeProtocol, maxSuccessors)
+	for i, successor := range successors {
+		if successor == id {
+			node.successors[i] = node
+		} else {
+			node.successors[i] = &LocalVNode{id: successor}
+		}
+	}
+	return node
+}
+
+func TestEstimateSize(t *testing.T) {
+	quarter := uint64(1) << 62
+	tests := []struct {
+		name string
+		node *LocalVNode
+		want float64
+	}{
+		{"no successor", vnodeWithSuccessors(1, 3), 1},
+		{"alone", vnodeWithSuccessors(1, 3, 1), 1},
+		{"partial list", vnodeWithSuccessors(1, 3, 5, 9), 3},
+		{"full list", vnodeWithSuccessors(0, 2, quarter, 2*quarter), 4},
+		{"full list wrapping around zero", vnodeWithSuccessors(3*quarter, 3, 0, quarter, 2*quarter), 4},
+		{"list wrapped to itself", vnodeWithSuccessors(1, 3, 5, 1, 5), 2},
+		{"list repeating a VNode", vnodeWithSuccessors(1, 2, 5, 5), 2},
+	}
+	for _, test := range tests {
+		if got := test.node.EstimateSize(); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: EstimateSize() = %g, want %g", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAggregateMerge(t *testing.T) {
+	empty := Aggregate{}
+	a := Aggregate{Count: 2, Sum: 3, Min: 1, Max: 2}
+	b := Aggregate{Count: 1, Sum: -4, Min: -4, Max: -4}
+
+	tests := []struct {
+		name string
+		a, b Aggregate
+		want Aggregate
+	}{
+		{"empty", empty, empty, empty},
+		{"into empty", empty, a, a},
+		{"empty into", a, empty, a},
+		{"both", a, b, Aggregate{Count: 3, Sum: -1, Min: -4, Max: 2}},
+	}
+	for _, test := range tests {
+		if got := test.a.merge(test.b); got != test.want {
+			t.Errorf("%s: merge() = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+	if mean := empty.Mean(); mean != 0 {
+		t.Errorf("Mean() of no values = %g, want 0", mean)
+	}
+}
+
+func TestAggregatorSkipsMissingReplies(t *testing.T) {
+	reply := func(stats map[string]Aggregate) []byte {
+		payload, err := encodeGob(stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return payload
+	}
+
+	payload := aggregator{}.Aggregate([][]byte{
+		reply(map[string]Aggregate{LoadStat: {Count: 1, Sum: 5, Min: 5, Max: 5}}),
+		nil,
+		[]byte("garbage"),
+		reply(map[string]Aggregate{}),
+		reply(map[string]Aggregate{LoadStat: {Count: 1, Sum: 7, Min: 7, Max: 7}, ShedStat: {Count: 1}}),
+	})
+
+	var stats map[string]Aggregate
+	if err := decodeGob(payload, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Aggregate{Count: 2, Sum: 12, Min: 5, Max: 7}); stats[LoadStat] != want {
+		t.Errorf("%s = %+v, want %+v", LoadStat, stats[LoadStat], want)
+	}
+	if want := (Aggregate{Count: 1}); stats[ShedStat] != want {
+		t.Errorf("%s = %+v, want %+v", ShedStat, stats[ShedStat], want)
+	}
+}
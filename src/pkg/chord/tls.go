@@ -0,0 +1,53 @@
+package chord
+
+// TLS for the RPC transport.
+// A single tls.Config secures both sides of the ring's RPC traffic: its
+// certificate is presented by the RPC servers of the VNodes and, with mutual
+// TLS, by their clients, and its trust root verifies the certificates of peers.
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// LoadTLSConfig creates the TLS configuration of the RPC transport.
+// certFile and keyFile hold the PEM certificate and key presented to peers and
+// may be empty for clients not running VNodes. caFile holds the PEM certificates
+// of the trust root peers are verified against, the system roots are used if it
+// is empty. mutual requires clients to present a certificate signed by the trust root.
+func LoadTLSConfig(certFile string, keyFile string, caFile string, mutual bool) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: loading key pair: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: reading CA: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificates in CA file %s", caFile)
+		}
+		config.RootCAs = pool
+		config.ClientCAs = pool
+	}
+
+	if mutual {
+		if caFile == "" {
+			return nil, errors.New("tls: mutual TLS requires a CA")
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
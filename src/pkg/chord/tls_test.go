@@ -0,0 +1,149 @@
+package chord
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a generated certificate and its key.
+type testCert struct {
+	cert *x509.Certificate
+	der  []byte
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert generates a certificate for 127.0.0.1 signed by parent, or
+// self-signed CA certificate if parent is nil.
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, der: der, key: key}
+}
+
+// write writes the PEM certificate and key to dir and returns their paths.
+func (c *testCert) write(t *testing.T, dir string, name string) (string, string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+".key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// tlsCert returns c as a tls.Certificate.
+func (c *testCert) tlsCert() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+// testRing creates a ring of one VNode on the loopback interface failing fast.
+func testRing(t *testing.T, opts ...Option) *Ring {
+	opts = append([]Option{
+		WithHostname("127.0.0.1:0"),
+		WithDialTimeout(time.Second),
+		WithCallTimeout(time.Second),
+		WithJoinPolicy(JoinPolicy{Timeout: time.Second, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 200 * time.Millisecond}),
+	}, opts...)
+	ring, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+func TestTLSRejectsUntrustedCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chord-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	nodeFile, nodeKey := newTestCert(t, "node", ca).write(t, dir, "node")
+	otherCA := newTestCert(t, "other-ca", nil)
+	otherCAFile, _ := otherCA.write(t, dir, "other-ca")
+	strangerFile, strangerKey := newTestCert(t, "stranger", otherCA).write(t, dir, "stranger")
+
+	trusted, err := LoadTLSConfig(nodeFile, nodeKey, caFile, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := testRing(t, WithTLS(trusted))
+	if err := seed.Create(); err != nil {
+		t.Fatal(err)
+	}
+	defer seed.Leave()
+
+	tests := []struct {
+		name                   string
+		cert, key, ca          string
+		mutual, wantJoinFailed bool
+	}{
+		{"trusted", nodeFile, nodeKey, caFile, true, false},
+		{"client certificate from another CA", strangerFile, strangerKey, caFile, true, true},
+		{"server certificate from another CA", strangerFile, strangerKey, otherCAFile, false, true},
+	}
+	for _, test := range tests {
+		config, err := LoadTLSConfig(test.cert, test.key, test.ca, test.mutual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ring := testRing(t, WithTLS(config))
+		err = ring.Join(seed.Hostnames()[0])
+		if failed := err != nil; failed != test.wantJoinFailed {
+			t.Errorf("%s: Join() error = %v, want failure %v", test.name, err, test.wantJoinFailed)
+		}
+		if err == nil {
+			ring.Leave()
+		} else {
+			ring.closeServers()
+		}
+	}
+}
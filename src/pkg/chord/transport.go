@@ -0,0 +1,72 @@
+package chord
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// Transport creates RPC backed RemoteVNodes and carries the settings
+// shared by the RPC clients and servers of a Ring.
+type Transport struct {
+	log         *Logging.Logger
+	dialTimeout time.Duration
+	callTimeout time.Duration
+	// tls secures RPC connections if set.
+	tls *tls.Config
+	// identities verifies the identities of remote VNodes if set.
+	identities *identities
+	// admission limits the requests served by the RPC servers, all are admitted if nil.
+	admission *Admission
+}
+
+func newTransport(log *Logging.Logger, dialTimeout time.Duration, callTimeout time.Duration, tls *tls.Config) *Transport {
+	return &Transport{
+		log:         log,
+		dialTimeout: dialTimeout,
+		callTimeout: callTimeout,
+		tls:         tls,
+	}
+}
+
+// NewTransport creates a Transport for talking to VNodes of a ring without running any.
+func NewTransport(opts ...Option) *Transport {
+	options := DefaultOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return newTransport(options.Logs.Logger("rpc"), options.DialTimeout, options.CallTimeout, options.TLS)
+}
+
+// remote returns the RemoteVNode at hostname referred to by a remote VNode.
+// If the transport verifies identities, identity must be valid and sets the ID.
+func (t *Transport) remote(hostname string, identity VNode.Identity) (*RemoteVNode, error) {
+	vnode := InitRemoteVNode(hostname, t)
+	if t.identities == nil {
+		return vnode, nil
+	}
+
+	id, err := t.identities.verify(identity)
+	if err == nil && identity.Hostname != hostname {
+		err = fmt.Errorf("%v: identity of %s claims %s", ErrIdentity, hostname, identity.Hostname)
+	}
+	if err != nil {
+		t.log.Warn("Rejected VNode reference", "vnode", hostname, "err", err)
+		return nil, err
+	}
+
+	vnode.id = id
+	vnode.identity = identity
+	return vnode, nil
+}
+
+// Remote returns a RemoteVNode reached over RPC at hostname. Its ID is the
+// hash of hostname, references to VNodes with identities are only to be used
+// to reach them.
+func (t *Transport) Remote(hostname string) *RemoteVNode {
+	return InitRemoteVNode(hostname, t)
+}
@@ -0,0 +1,211 @@
+package chord
+
+// Invariant checks.
+// Every VNode periodically verifies the invariants of its neighbourhood and
+// reports violations to the Ring, which counts them and keeps the recent ones.
+// A VNode the seeds do not route to is part of a loopy or disjoint ring, a
+// state stabilization cannot leave, and re-joins the ring through a seed.
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	Util "github.com/arush15june/chord-golang/src/pkg/util"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// Invariants checked by the verifier.
+const (
+	// SuccessorPredecessorInvariant requires the successor's predecessor to be the VNode.
+	SuccessorPredecessorInvariant = "successor-predecessor"
+	// PredecessorSuccessorInvariant requires the predecessor's successor to be the VNode.
+	PredecessorSuccessorInvariant = "predecessor-successor"
+	// FingerInvariant requires a finger to be the successor of its start.
+	FingerInvariant = "finger"
+	// UniqueIDInvariant requires neighbouring VNodes to have distinct IDs.
+	UniqueIDInvariant = "unique-id"
+	// ConnectedInvariant requires the seeds to route the VNode's ID to the VNode,
+	// it is violated in loopy and disjoint rings.
+	ConnectedInvariant = "connected"
+)
+
+// ViolationsStat is the number of invariant violations found by a VNode.
+const ViolationsStat = "violations"
+
+// maxRecentViolations is the number of violations kept by the Ring.
+const maxRecentViolations = 100
+
+// rejoinAfter is the number of consecutive checks a VNode must be
+// disconnected from the seeds before it re-joins.
+const rejoinAfter = 2
+
+// Violation is an invariant violation found by a VNode.
+type Violation struct {
+	Time      time.Time `json:"time"`
+	VNode     string    `json:"vnode"`
+	Invariant string    `json:"invariant"`
+	Detail    string    `json:"detail"`
+}
+
+// report records a violation of invariant found by the VNode.
+func (node *LocalVNode) report(invariant string, format string, args ...interface{}) {
+	violation := Violation{
+		Time:      time.Now(),
+		VNode:     node.Hostname(),
+		Invariant: invariant,
+		Detail:    fmt.Sprintf(format, args...),
+	}
+
+	atomic.AddUint64(&node.violations, 1)
+	node.log.Warn("Invariant violated", "invariant", invariant, "detail", violation.Detail)
+	node.ring.recordViolation(violation)
+}
+
+// recordViolation counts violation and keeps it among the recent violations.
+func (ring *Ring) recordViolation(violation Violation) {
+	ring.violationsMu.Lock()
+	ring.violationCounts[violation.Invariant]++
+	ring.recentViolations = append(ring.recentViolations, violation)
+	if len(ring.recentViolations) > maxRecentViolations {
+		ring.recentViolations = ring.recentViolations[1:]
+	}
+	ring.violationsMu.Unlock()
+
+	if handler := ring.options.ViolationHandler; handler != nil {
+		handler(violation)
+	}
+}
+
+// Violations returns the number of violations of every invariant
+// and the most recent violations, oldest first.
+func (ring *Ring) Violations() (map[string]uint64, []Violation) {
+	ring.violationsMu.Lock()
+	defer ring.violationsMu.Unlock()
+
+	counts := make(map[string]uint64, len(ring.violationCounts))
+	for invariant, count := range ring.violationCounts {
+		counts[invariant] = count
+	}
+	recent := make([]Violation, len(ring.recentViolations))
+	copy(recent, ring.recentViolations)
+
+	return counts, recent
+}
+
+// Verify checks the invariants of the VNode's neighbourhood and re-joins
+// the ring if the VNode has been disconnected from the seeds.
+func (node *LocalVNode) Verify() {
+	node.log.Debug("Verifying invariants")
+
+	successor := node.successor()
+	predecessor := node.currentPredecessor()
+
+	if successor != nil && successor.ID() != node.ID() {
+		node.verifySuccessor(successor)
+	}
+	if predecessor != nil && predecessor.ID() != node.ID() {
+		node.verifyPredecessor(predecessor)
+	}
+	node.verifyFinger()
+	node.verifyConnected()
+}
+
+func (node *LocalVNode) verifySuccessor(successor VNode.VNodeProtocol) {
+	if successor.Hostname() != node.Hostname() && successor.ID() == node.ID() {
+		node.report(UniqueIDInvariant, "successor %s has the same ID", successor.Hostname())
+	}
+
+	predecessor, err := successor.GetPredecessor()
+	if err != nil {
+		node.report(SuccessorPredecessorInvariant, "successor %s has no predecessor: %v", successor.Hostname(), err)
+		return
+	}
+	if predecessor.ID() != node.ID() {
+		node.report(SuccessorPredecessorInvariant, "predecessor of successor %s is %s", successor.Hostname(), predecessor.Hostname())
+	}
+}
+
+func (node *LocalVNode) verifyPredecessor(predecessor VNode.VNodeProtocol) {
+	if predecessor.Hostname() != node.Hostname() && predecessor.ID() == node.ID() {
+		node.report(UniqueIDInvariant, "predecessor %s has the same ID", predecessor.Hostname())
+	}
+
+	successors, err := predecessor.FindSuccessors(1)
+	if err != nil || len(successors) == 0 {
+		node.report(PredecessorSuccessorInvariant, "predecessor %s has no successor: %v", predecessor.Hostname(), err)
+		return
+	}
+	if successors[0].ID() != node.ID() {
+		node.report(PredecessorSuccessorInvariant, "successor of predecessor %s is %s", predecessor.Hostname(), successors[0].Hostname())
+	}
+}
+
+// verifyFinger checks one finger per call, in turn. A finger is the successor
+// of its start if the start lies between the finger's predecessor and the finger.
+func (node *LocalVNode) verifyFinger() {
+	fingers := node.fingerTable()
+	if len(fingers) == 0 {
+		return
+	}
+
+	index := int(atomic.AddUint64(&node.verifiedFinger, 1) % uint64(len(fingers)))
+	finger := fingers[index]
+	if finger == nil || finger.ID() == node.ID() {
+		return
+	}
+
+	start := node.ID() + uint64(math.Exp2(float64(index)))
+	predecessor, err := finger.GetPredecessor()
+	if err != nil {
+		return
+	}
+	if !Util.IsBetweenID(start, predecessor.ID(), finger.ID()) {
+		node.report(FingerInvariant, "finger %d is %s, but its predecessor %s succeeds the start %d", index+1, finger.Hostname(), predecessor.Hostname(), start)
+	}
+}
+
+// verifyConnected asks the first reachable seed for the successor of the VNode's ID,
+// which is the VNode itself if the seed is on the same ring. A VNode disconnected
+// for rejoinAfter consecutive checks re-joins through the seed.
+func (node *LocalVNode) verifyConnected() {
+	for _, seed := range node.ring.rejoinSeeds() {
+		owner, err := seed.FindSuccessor(node.ID())
+		if err != nil {
+			continue
+		}
+
+		if owner.ID() == node.ID() {
+			atomic.StoreInt32(&node.disconnected, 0)
+			return
+		}
+
+		node.report(ConnectedInvariant, "seed %s routes the VNode's ID to %s, the ring is loopy or disjoint", seed.Hostname(), owner.Hostname())
+		if atomic.AddInt32(&node.disconnected, 1) < rejoinAfter {
+			return
+		}
+
+		node.log.Warn("Re-joining the ring", "seed", seed.Hostname())
+		if err := node.Join(seed); err != nil {
+			node.log.Error("Failed to re-join the ring", "seed", seed.Hostname(), "err", err)
+			return
+		}
+		atomic.StoreInt32(&node.disconnected, 0)
+		return
+	}
+}
+
+// VerifyRoutine verifies the invariants periodically.
+func (node *LocalVNode) VerifyRoutine() {
+	for {
+		timer := time.NewTimer(node.verifyInterval)
+		select {
+		case <-timer.C:
+			node.Verify()
+		case <-node.stopVerifyChan:
+			timer.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,630 @@
+package config
+
+// Node configuration.
+// A Config is built from defaults, overridden by an optional config file,
+// overridden by command line flags. Config files are flat key/value
+// documents accepted both as YAML (key: value) and as TOML (key = value),
+// keys are the same as the command line flag names.
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+)
+
+const (
+	// MaxFingers is the largest finger table for 64 bit identifiers.
+	MaxFingers = 64
+	// MaxIdentityWorkers is the most workers deriving their IDs from one certificate.
+	MaxIdentityWorkers = 64
+)
+
+// Config holds the effective configuration of a node.
+type Config struct {
+	// Mode is "create", "join" or "walk".
+	Mode string
+	// Workers is the number of virtual nodes to start.
+	Workers int
+	// Host is the self hostname of the physical node.
+	Host string
+	// RemoteHost is a comma separated list of seed nodes of the ring to join.
+	RemoteHost string
+	// Discovery selects how seeds are discovered: "none", "mdns" or "file".
+	Discovery string
+	// DiscoveryDir is the shared seed directory of the "file" discovery.
+	DiscoveryDir string
+	// DiscoveryTimeout bounds waiting for mDNS answers.
+	DiscoveryTimeout time.Duration
+	// JoinTimeout bounds the time spent joining the ring.
+	JoinTimeout time.Duration
+	// JoinBackoff is the initial wait between rounds of join attempts.
+	JoinBackoff time.Duration
+	// JoinMaxBackoff caps the wait between rounds of join attempts.
+	JoinMaxBackoff time.Duration
+	// HTTPPort is the port of the HTTP API.
+	HTTPPort string
+	// ShutdownTimeout bounds draining the HTTP API on shutdown.
+	ShutdownTimeout time.Duration
+	// DataDir is the directory persisting the keys of the VNodes, keys are held in memory if empty.
+	DataDir string
+	// CompactInterval is the period between compactions of the write-ahead logs in DataDir.
+	CompactInterval time.Duration
+	// PersistInterval is the period between saves of the routing state to DataDir.
+	PersistInterval time.Duration
+	// Replicas is the number of VNodes storing every key.
+	Replicas int
+	// ReadConsistency and WriteConsistency are the default consistencies of
+	// reads and writes: one, quorum or all.
+	ReadConsistency  string
+	WriteConsistency string
+
+	// HTTPAuth is the credentials file of the HTTP API, authentication is disabled if empty.
+	HTTPAuth string
+	// HTTPTLS serves the HTTP API over TLS with TLSCert, clients may authenticate with certificates signed by TLSCA.
+	HTTPTLS bool
+
+	// TLSCert and TLSKey are the PEM certificate and key securing the RPC traffic of the VNodes.
+	TLSCert string
+	TLSKey  string
+	// TLSCA is the PEM trust root peer certificates are verified against.
+	TLSCA string
+	// MutualTLS requires peers to present certificates signed by TLSCA.
+	MutualTLS bool
+	// Identity derives VNode IDs from the TLS certificate and requires peers to prove theirs.
+	Identity bool
+
+	// RateLimit is the number of requests per second served to a single peer address, 0 disables the limit.
+	RateLimit float64
+	// GlobalRateLimit is the number of requests per second served to all peers together, 0 disables the limit.
+	GlobalRateLimit float64
+	// Burst is the number of requests served at once beyond the rate limits.
+	Burst int
+	// MaxConns bounds the concurrent RPC connections and HTTP requests, 0 disables the limit.
+	MaxConns int
+
+	// LogLevel is the default log level.
+	LogLevel string
+	// LogFormat is the log encoding, "logfmt" or "json".
+	LogFormat string
+	// LogLevels holds per component log levels.
+	LogLevels string
+
+	// MinStabilizeInterval is the stabilization interval while the ring is changing.
+	MinStabilizeInterval time.Duration
+	// MaxStabilizeInterval is the stabilization interval backed off to while the ring is quiet.
+	MaxStabilizeInterval time.Duration
+	// FixFingerInterval is the period between fixing two fingers while the ring is changing.
+	FixFingerInterval time.Duration
+	// MaxFixFingerInterval is the period between fixing two fingers backed off to while the ring is quiet.
+	MaxFixFingerInterval time.Duration
+	// CheckPredInterval is the period between predecessor liveness checks.
+	CheckPredInterval time.Duration
+	// PhiThreshold is the suspicion above which unresponsive VNodes are declared dead.
+	PhiThreshold float64
+	// VerifyInterval is the period between invariant checks, 0 disables them.
+	VerifyInterval time.Duration
+	// MaxSuccessors is the size of the successor table.
+	MaxSuccessors int
+	// MaxFingers is the size of the finger table.
+	MaxFingers int
+}
+
+// Default returns the default configuration.
+func Default() *Config {
+	return &Config{
+		Mode:       "create",
+		Workers:    1,
+		Host:       ":0",
+		RemoteHost: "127.0.0.1:8000",
+
+		Discovery:        "none",
+		DiscoveryDir:     "",
+		DiscoveryTimeout: 2 * time.Second,
+
+		JoinTimeout:    time.Minute,
+		JoinBackoff:    time.Second,
+		JoinMaxBackoff: 30 * time.Second,
+
+		HTTPPort: "8090",
+
+		ShutdownTimeout: 10 * time.Second,
+
+		DataDir:         "",
+		CompactInterval: time.Minute,
+		PersistInterval: 30 * time.Second,
+
+		Replicas:         1,
+		ReadConsistency:  "quorum",
+		WriteConsistency: "quorum",
+
+		HTTPAuth: "",
+		HTTPTLS:  false,
+
+		TLSCert:   "",
+		TLSKey:    "",
+		TLSCA:     "",
+		MutualTLS: false,
+		Identity:  false,
+
+		RateLimit:       0,
+		GlobalRateLimit: 0,
+		Burst:           20,
+		MaxConns:        1024,
+
+		LogLevel:  "info",
+		LogFormat: "logfmt",
+		LogLevels: "",
+
+		MinStabilizeInterval: time.Second,
+		MaxStabilizeInterval: 45 * time.Second,
+		FixFingerInterval:    time.Second,
+		MaxFixFingerInterval: 45 * time.Second,
+		CheckPredInterval:    15 * time.Second,
+		PhiThreshold:         8,
+		VerifyInterval:       30 * time.Second,
+		MaxSuccessors:        1,
+		MaxFingers:           6,
+	}
+}
+
+// field binds a config key to accessors of a Config field.
+type field struct {
+	get func(c *Config) string
+	set func(c *Config, value string) error
+}
+
+func stringField(ptr func(c *Config) *string) field {
+	return field{
+		get: func(c *Config) string { return *ptr(c) },
+		set: func(c *Config, value string) error {
+			*ptr(c) = value
+			return nil
+		},
+	}
+}
+
+func boolField(ptr func(c *Config) *bool) field {
+	return field{
+		get: func(c *Config) string { return strconv.FormatBool(*ptr(c)) },
+		set: func(c *Config, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid boolean %q", value)
+			}
+			*ptr(c) = b
+			return nil
+		},
+	}
+}
+
+func intField(ptr func(c *Config) *int) field {
+	return field{
+		get: func(c *Config) string { return strconv.Itoa(*ptr(c)) },
+		set: func(c *Config, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid integer %q", value)
+			}
+			*ptr(c) = n
+			return nil
+		},
+	}
+}
+
+func floatField(ptr func(c *Config) *float64) field {
+	return field{
+		get: func(c *Config) string { return strconv.FormatFloat(*ptr(c), 'g', -1, 64) },
+		set: func(c *Config, value string) error {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid number %q", value)
+			}
+			*ptr(c) = f
+			return nil
+		},
+	}
+}
+
+func durationField(ptr func(c *Config) *time.Duration) field {
+	return field{
+		get: func(c *Config) string { return ptr(c).String() },
+		set: func(c *Config, value string) error {
+			d, err := ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			*ptr(c) = d
+			return nil
+		},
+	}
+}
+
+// fields maps config keys, which are also the flag names, to Config fields.
+var fields = map[string]field{
+	"mode":             stringField(func(c *Config) *string { return &c.Mode }),
+	"workers":          intField(func(c *Config) *int { return &c.Workers }),
+	"host":             stringField(func(c *Config) *string { return &c.Host }),
+	"rhost":            stringField(func(c *Config) *string { return &c.RemoteHost }),
+	"discovery":        stringField(func(c *Config) *string { return &c.Discovery }),
+	"discoverydir":     stringField(func(c *Config) *string { return &c.DiscoveryDir }),
+	"discoverytimeout": durationField(func(c *Config) *time.Duration { return &c.DiscoveryTimeout }),
+	"jointimeout":      durationField(func(c *Config) *time.Duration { return &c.JoinTimeout }),
+	"joinbackoff":      durationField(func(c *Config) *time.Duration { return &c.JoinBackoff }),
+	"joinmaxbackoff":   durationField(func(c *Config) *time.Duration { return &c.JoinMaxBackoff }),
+	"httpport":         stringField(func(c *Config) *string { return &c.HTTPPort }),
+	"shutdowntimeout":  durationField(func(c *Config) *time.Duration { return &c.ShutdownTimeout }),
+	"datadir":          stringField(func(c *Config) *string { return &c.DataDir }),
+	"compact":          durationField(func(c *Config) *time.Duration { return &c.CompactInterval }),
+	"persist":          durationField(func(c *Config) *time.Duration { return &c.PersistInterval }),
+	"replicas":         intField(func(c *Config) *int { return &c.Replicas }),
+	"readconsistency":  stringField(func(c *Config) *string { return &c.ReadConsistency }),
+	"writeconsistency": stringField(func(c *Config) *string { return &c.WriteConsistency }),
+	"httpauth":         stringField(func(c *Config) *string { return &c.HTTPAuth }),
+	"httptls":          boolField(func(c *Config) *bool { return &c.HTTPTLS }),
+	"tlscert":          stringField(func(c *Config) *string { return &c.TLSCert }),
+	"tlskey":           stringField(func(c *Config) *string { return &c.TLSKey }),
+	"tlsca":            stringField(func(c *Config) *string { return &c.TLSCA }),
+	"mtls":             boolField(func(c *Config) *bool { return &c.MutualTLS }),
+	"identity":         boolField(func(c *Config) *bool { return &c.Identity }),
+	"ratelimit":        floatField(func(c *Config) *float64 { return &c.RateLimit }),
+	"globalratelimit":  floatField(func(c *Config) *float64 { return &c.GlobalRateLimit }),
+	"burst":            intField(func(c *Config) *int { return &c.Burst }),
+	"maxconns":         intField(func(c *Config) *int { return &c.MaxConns }),
+	"loglevel":         stringField(func(c *Config) *string { return &c.LogLevel }),
+	"logformat":        stringField(func(c *Config) *string { return &c.LogFormat }),
+	"loglevels":        stringField(func(c *Config) *string { return &c.LogLevels }),
+	"minstabilize":     durationField(func(c *Config) *time.Duration { return &c.MinStabilizeInterval }),
+	"maxstabilize":     durationField(func(c *Config) *time.Duration { return &c.MaxStabilizeInterval }),
+	"fixfinger":        durationField(func(c *Config) *time.Duration { return &c.FixFingerInterval }),
+	"maxfixfinger":     durationField(func(c *Config) *time.Duration { return &c.MaxFixFingerInterval }),
+	"checkpred":        durationField(func(c *Config) *time.Duration { return &c.CheckPredInterval }),
+	"phi":              floatField(func(c *Config) *float64 { return &c.PhiThreshold }),
+	"verify":           durationField(func(c *Config) *time.Duration { return &c.VerifyInterval }),
+	"successors":       intField(func(c *Config) *int { return &c.MaxSuccessors }),
+	"fingers":          intField(func(c *Config) *int { return &c.MaxFingers }),
+}
+
+// ParseDuration parses a Go duration string such as "500ms" or "1m30s".
+// A bare number is interpreted as whole seconds.
+func ParseDuration(value string) (time.Duration, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+// IsKey reports whether key is a known config key.
+func IsKey(key string) bool {
+	_, ok := fields[key]
+	return ok
+}
+
+// Set sets the field identified by key from its string representation.
+func (c *Config) Set(key string, value string) error {
+	f, ok := fields[key]
+	if !ok {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+
+	if err := f.set(c, value); err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	return nil
+}
+
+// Get returns the string representation of the field identified by key.
+func (c *Config) Get(key string) (string, error) {
+	f, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("unknown config key %q", key)
+	}
+
+	return f.get(c), nil
+}
+
+// Keys returns all config keys in sorted order.
+func Keys() []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Values returns the string representation of every field keyed by config key.
+func (c *Config) Values() map[string]string {
+	values := make(map[string]string, len(fields))
+	for key, f := range fields {
+		values[key] = f.get(c)
+	}
+
+	return values
+}
+
+// String formats the config as a YAML document which can be loaded back.
+func (c *Config) String() string {
+	var b strings.Builder
+	for _, key := range Keys() {
+		fmt.Fprintf(&b, "%s: %q\n", key, fields[key].get(c))
+	}
+
+	return b.String()
+}
+
+// LoadFile overrides the config with the values in the file at path.
+func (c *Config) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := c.Load(f); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// Load overrides the config with the key/value pairs read from r.
+func (c *Config) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" || line == "---" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return fmt.Errorf("line %d: tables are not supported, keys must be top level", lineNumber)
+		}
+
+		sep := strings.IndexAny(line, ":=")
+		if sep < 0 {
+			return fmt.Errorf("line %d: expected key: value or key = value", lineNumber)
+		}
+
+		key := strings.TrimSpace(line[:sep])
+		value, err := unquote(strings.TrimSpace(line[sep+1:]))
+		if err != nil {
+			return fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+
+		if err := c.Set(key, value); err != nil {
+			return fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// stripComment removes a trailing # comment which is not inside quotes.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == '#':
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// unquote removes the quotes around a quoted string value.
+func unquote(value string) (string, error) {
+	if len(value) < 2 {
+		return value, nil
+	}
+
+	switch {
+	case value[0] == '"' && value[len(value)-1] == '"':
+		return strconv.Unquote(value)
+	case value[0] == '\'' && value[len(value)-1] == '\'':
+		return value[1 : len(value)-1], nil
+	}
+
+	return value, nil
+}
+
+// SetFlags overrides the config with the flags of flags set on the command
+// line which are config keys. Flags left unset keep the values of the file.
+func (c *Config) SetFlags(flags *flag.FlagSet) error {
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if err != nil || !IsKey(f.Name) {
+			return
+		}
+		err = c.Set(f.Name, f.Value.String())
+	})
+
+	return err
+}
+
+// Seeds returns the seed hostnames listed in RemoteHost.
+func (c *Config) Seeds() []string {
+	seeds := make([]string, 0)
+	for _, seed := range strings.Split(c.RemoteHost, ",") {
+		seed = strings.TrimSpace(seed)
+		if seed != "" {
+			seeds = append(seeds, seed)
+		}
+	}
+
+	return seeds
+}
+
+// Validate checks the config for invalid or inconsistent values.
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case "create", "join", "walk":
+	default:
+		return fmt.Errorf("mode: must be 'create', 'join' or 'walk', got %q", c.Mode)
+	}
+
+	if c.Workers < 1 {
+		return fmt.Errorf("workers: must be at least 1, got %d", c.Workers)
+	}
+	switch c.Discovery {
+	case "none", "mdns":
+	case "file":
+		if c.DiscoveryDir == "" {
+			return fmt.Errorf("discoverydir: required with file discovery")
+		}
+	default:
+		return fmt.Errorf("discovery: must be 'none', 'mdns' or 'file', got %q", c.Discovery)
+	}
+	if c.Discovery == "mdns" && unspecified(c.Host) {
+		return fmt.Errorf("host: mdns discovery advertises the VNode hostnames, an address other nodes can dial is required, got %q", c.Host)
+	}
+	if c.DiscoveryTimeout <= 0 {
+		return fmt.Errorf("discoverytimeout: must be positive, got %s", c.DiscoveryTimeout)
+	}
+	if c.Mode == "join" && len(c.Seeds()) == 0 && c.Discovery == "none" {
+		return fmt.Errorf("rhost: at least one seed or a discovery method is required in join mode")
+	}
+	if c.Mode == "walk" && len(c.Seeds()) == 0 {
+		return fmt.Errorf("rhost: a VNode to start from is required in walk mode")
+	}
+	if c.JoinTimeout <= 0 {
+		return fmt.Errorf("jointimeout: must be positive, got %s", c.JoinTimeout)
+	}
+	if c.JoinBackoff <= 0 {
+		return fmt.Errorf("joinbackoff: must be positive, got %s", c.JoinBackoff)
+	}
+	if c.JoinMaxBackoff < c.JoinBackoff {
+		return fmt.Errorf("joinmaxbackoff: must not be less than joinbackoff (%s), got %s", c.JoinBackoff, c.JoinMaxBackoff)
+	}
+	if port, err := strconv.Atoi(c.HTTPPort); err != nil || port < 0 || port > 65535 {
+		return fmt.Errorf("httpport: invalid port %q", c.HTTPPort)
+	}
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("shutdowntimeout: must be positive, got %s", c.ShutdownTimeout)
+	}
+	if c.CompactInterval < 0 {
+		return fmt.Errorf("compact: must not be negative, got %s", c.CompactInterval)
+	}
+	if c.PersistInterval <= 0 {
+		return fmt.Errorf("persist: must be positive, got %s", c.PersistInterval)
+	}
+	if c.Replicas < 1 || c.Replicas > c.MaxSuccessors+1 {
+		return fmt.Errorf("replicas: must be between 1 and successors + 1 (%d), got %d", c.MaxSuccessors+1, c.Replicas)
+	}
+	if !validConsistency(c.ReadConsistency) {
+		return fmt.Errorf("readconsistency: unknown consistency %q, use one, quorum or all", c.ReadConsistency)
+	}
+	if !validConsistency(c.WriteConsistency) {
+		return fmt.Errorf("writeconsistency: unknown consistency %q, use one, quorum or all", c.WriteConsistency)
+	}
+
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("tlscert, tlskey: both or neither are required")
+	}
+	if c.TLSCA != "" && c.TLSCert == "" && c.Mode != "walk" {
+		return fmt.Errorf("tlscert: required with tlsca, VNodes present a certificate to peers")
+	}
+	if c.MutualTLS && c.TLSCA == "" {
+		return fmt.Errorf("tlsca: required with mtls")
+	}
+	if c.Identity && c.TLSCert == "" && c.Mode != "walk" {
+		return fmt.Errorf("tlscert: required with identity, VNode IDs are derived from it")
+	}
+	if c.Identity && c.Workers > MaxIdentityWorkers {
+		return fmt.Errorf("workers: at most %d with identity, got %d", MaxIdentityWorkers, c.Workers)
+	}
+	if c.HTTPTLS && c.TLSCert == "" {
+		return fmt.Errorf("tlscert: required with httptls")
+	}
+
+	if c.RateLimit < 0 {
+		return fmt.Errorf("ratelimit: must not be negative, got %g", c.RateLimit)
+	}
+	if c.GlobalRateLimit < 0 {
+		return fmt.Errorf("globalratelimit: must not be negative, got %g", c.GlobalRateLimit)
+	}
+	if c.Burst < 1 {
+		return fmt.Errorf("burst: must be at least 1, got %d", c.Burst)
+	}
+	if c.MaxConns < 0 {
+		return fmt.Errorf("maxconns: must not be negative, got %d", c.MaxConns)
+	}
+
+	if _, err := Logging.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("loglevel: %v", err)
+	}
+	if _, err := Logging.ParseFormat(c.LogFormat); err != nil {
+		return fmt.Errorf("logformat: %v", err)
+	}
+	if err := Logging.NewRegistry(ioutil.Discard, Logging.LogfmtFormat, Logging.InfoLevel).SetLevels(c.LogLevels); err != nil {
+		return fmt.Errorf("loglevels: %v", err)
+	}
+
+	if c.MinStabilizeInterval <= 0 {
+		return fmt.Errorf("minstabilize: must be positive, got %s", c.MinStabilizeInterval)
+	}
+	if c.MaxStabilizeInterval < c.MinStabilizeInterval {
+		return fmt.Errorf("maxstabilize: must not be less than minstabilize (%s), got %s", c.MinStabilizeInterval, c.MaxStabilizeInterval)
+	}
+	if c.FixFingerInterval <= 0 {
+		return fmt.Errorf("fixfinger: must be positive, got %s", c.FixFingerInterval)
+	}
+	if c.MaxFixFingerInterval < c.FixFingerInterval {
+		return fmt.Errorf("maxfixfinger: must not be less than fixfinger (%s), got %s", c.FixFingerInterval, c.MaxFixFingerInterval)
+	}
+	if c.CheckPredInterval <= 0 {
+		return fmt.Errorf("checkpred: must be positive, got %s", c.CheckPredInterval)
+	}
+	if c.PhiThreshold <= 0 {
+		return fmt.Errorf("phi: must be positive, got %g", c.PhiThreshold)
+	}
+	if c.VerifyInterval < 0 {
+		return fmt.Errorf("verify: must not be negative, got %s", c.VerifyInterval)
+	}
+	if c.MaxSuccessors < 1 {
+		return fmt.Errorf("successors: must be at least 1, got %d", c.MaxSuccessors)
+	}
+	if c.MaxFingers < 1 || c.MaxFingers > MaxFingers {
+		return fmt.Errorf("fingers: must be between 1 and %d, got %d", MaxFingers, c.MaxFingers)
+	}
+
+	return nil
+}
+
+// unspecified reports whether host listens on all interfaces instead of
+// naming an address.
+func unspecified(host string) bool {
+	name, _, err := net.SplitHostPort(host)
+	if err != nil {
+		name = host
+	}
+	ip := net.ParseIP(name)
+
+	return name == "" || ip != nil && ip.IsUnspecified()
+}
+
+// validConsistency reports whether level names a consistency of the key-value store.
+func validConsistency(level string) bool {
+	return level == "one" || level == "quorum" || level == "all"
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want map[string]string
+	}{
+		{
+			"yaml",
+			"---\n# a node\nmode: join\nworkers: 3\nrhost: 127.0.0.1:8000\n\nmtls: true\n",
+			map[string]string{"mode": "join", "workers": "3", "rhost": "127.0.0.1:8000", "mtls": "true"},
+		},
+		{
+			"toml",
+			"mode = \"join\"\nworkers = 3 # VNodes\nrhost = \"127.0.0.1:8000\"\nphi = 4.5\n",
+			map[string]string{"mode": "join", "workers": "3", "rhost": "127.0.0.1:8000", "phi": "4.5"},
+		},
+		{
+			"values containing separators",
+			"loglevels: vnode=debug,rpc=warn\nrhost = 10.0.0.1:8000,10.0.0.2:8000\nhost: ':0'\n",
+			map[string]string{"loglevels": "vnode=debug,rpc=warn", "rhost": "10.0.0.1:8000,10.0.0.2:8000", "host": ":0"},
+		},
+		{
+			"quoted comments",
+			"datadir: \"/var/lib/chord#1\" # data\nhttpauth = '/etc/chord # auth'\n",
+			map[string]string{"datadir": "/var/lib/chord#1", "httpauth": "/etc/chord # auth"},
+		},
+		{
+			"durations",
+			"jointimeout: 90\njoinbackoff = \"1m30s\"\nminstabilize: 500ms\n",
+			map[string]string{"jointimeout": "1m30s", "joinbackoff": "1m30s", "minstabilize": "500ms"},
+		},
+	}
+	for _, test := range tests {
+		c := Default()
+		if err := c.Load(strings.NewReader(test.doc)); err != nil {
+			t.Errorf("%s: Load() error = %v", test.name, err)
+			continue
+		}
+		for key, want := range test.want {
+			if got, _ := c.Get(key); got != want {
+				t.Errorf("%s: %s = %q, want %q", test.name, key, got, want)
+			}
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want string
+	}{
+		{"mode: join\n[ring]\n", "line 2: tables are not supported"},
+		{"workers 3\n", "line 1: expected key: value or key = value"},
+		{"colour: blue\n", `line 1: unknown config key "colour"`},
+		{"workers: three\n", `line 1: workers: invalid integer "three"`},
+		{"\njointimeout = soon\n", `line 2: jointimeout: invalid duration "soon"`},
+		{"mtls: maybe\n", `line 1: mtls: invalid boolean "maybe"`},
+		{"rhost: \"127.0.0.1:8000\\x\"\n", "line 1: "},
+	}
+	for _, test := range tests {
+		err := Default().Load(strings.NewReader(test.doc))
+		if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("Load(%q) error = %v, want %q", test.doc, err, test.want)
+		}
+	}
+}
+
+func TestStringLoadsBack(t *testing.T) {
+	c := Default()
+	c.RemoteHost = "127.0.0.1:8000,127.0.0.1:8001"
+	c.LogLevels = "vnode=debug"
+	c.DataDir = `/tmp/"chord" #1`
+	c.JoinTimeout = 90 * time.Second
+
+	loaded := &Config{}
+	if err := loaded.Load(strings.NewReader(c.String())); err != nil {
+		t.Fatal(err)
+	}
+	want := c.Values()
+	for key, got := range loaded.Values() {
+		if got != want[key] {
+			t.Errorf("%s = %q, want %q", key, got, want[key])
+		}
+	}
+}
+
+func TestFlagsOverrideFile(t *testing.T) {
+	flags := flag.NewFlagSet("chord", flag.ContinueOnError)
+	workers := flags.Int("workers", 1, "")
+	flags.String("rhost", "127.0.0.1:8000", "")
+	flags.Duration("jointimeout", time.Minute, "")
+	flags.String("format", "json", "")
+	if err := flags.Parse([]string{"-workers", "5", "-jointimeout", "2m", "-format", "dot"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := Default()
+	if err := c.Load(strings.NewReader("workers: 3\nrhost: 10.0.0.1:8000\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetFlags(flags); err != nil {
+		t.Fatal(err)
+	}
+	if c.Workers != *workers {
+		t.Errorf("workers = %d, want the flag %d", c.Workers, *workers)
+	}
+	if c.JoinTimeout != 2*time.Minute {
+		t.Errorf("jointimeout = %s, want the flag 2m0s", c.JoinTimeout)
+	}
+	if c.RemoteHost != "10.0.0.1:8000" {
+		t.Errorf("rhost = %q, want the file's 10.0.0.1:8000 as the flag is unset", c.RemoteHost)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Default().Validate() = %v", err)
+	}
+
+	tests := []struct {
+		change func(c *Config)
+		want   string
+	}{
+		{func(c *Config) { c.Mode = "serve" }, "mode:"},
+		{func(c *Config) { c.Workers = 0 }, "workers:"},
+		{func(c *Config) { c.Discovery = "file" }, "discoverydir:"},
+		{func(c *Config) { c.Discovery = "mdns" }, "host:"},
+		{func(c *Config) { c.Discovery, c.Host = "mdns", "0.0.0.0:8000" }, "host:"},
+		{func(c *Config) { c.Discovery, c.Host = "mdns", "[::]:8000" }, "host:"},
+		{func(c *Config) { c.Mode, c.RemoteHost = "join", " , " }, "rhost:"},
+		{func(c *Config) { c.JoinMaxBackoff = c.JoinBackoff / 2 }, "joinmaxbackoff:"},
+		{func(c *Config) { c.HTTPPort = "http" }, "httpport:"},
+		{func(c *Config) { c.Replicas = c.MaxSuccessors + 2 }, "replicas:"},
+		{func(c *Config) { c.ReadConsistency = "most" }, "readconsistency:"},
+		{func(c *Config) { c.TLSCert = "node.pem" }, "tlscert, tlskey:"},
+		{func(c *Config) { c.MutualTLS = true }, "tlsca:"},
+		{func(c *Config) { c.LogLevels = "vnode" }, "loglevels:"},
+		{func(c *Config) { c.MaxStabilizeInterval = c.MinStabilizeInterval / 2 }, "maxstabilize:"},
+		{func(c *Config) { c.MaxFingers = MaxFingers + 1 }, "fingers:"},
+	}
+	for _, host := range []string{"10.0.0.1:8000", "node1:0", "[fe80::1]:8000"} {
+		c := Default()
+		c.Discovery, c.Host = "mdns", host
+		if err := c.Validate(); err != nil {
+			t.Errorf("Validate() with mdns on %s = %v", host, err)
+		}
+	}
+
+	for _, test := range tests {
+		c := Default()
+		test.change(c)
+		if err := c.Validate(); err == nil || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("Validate() = %v, want a %q error", err, test.want)
+		}
+	}
+}
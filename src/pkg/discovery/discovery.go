@@ -0,0 +1,37 @@
+package discovery
+
+// Discovery of existing ring members.
+// A Provider finds hostnames of live VNodes to be used as join seeds
+// and advertises the hostnames of the local VNodes to other processes.
+
+// Provider finds ring members and advertises local VNodes.
+type Provider interface {
+	// Seeds returns the hostnames of ring members known to the provider,
+	// excluding the ones registered by this process.
+	Seeds() ([]string, error)
+
+	// Register advertises hostnames of local VNodes until Deregister is called.
+	Register(hostnames []string) error
+
+	// Deregister withdraws the advertised hostnames.
+	Deregister() error
+}
+
+// excluding returns the hostnames not present in exclude, without duplicates.
+func excluding(hostnames []string, exclude []string) []string {
+	skip := make(map[string]bool, len(exclude)+len(hostnames))
+	for _, hostname := range exclude {
+		skip[hostname] = true
+	}
+
+	result := make([]string, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		if hostname == "" || skip[hostname] {
+			continue
+		}
+		skip[hostname] = true
+		result = append(result, hostname)
+	}
+
+	return result
+}
@@ -0,0 +1,158 @@
+package discovery
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const seedFileSuffix = ".seed"
+
+// FileRegistry discovers ring members through a directory shared between processes.
+// Every process owns one seed file listing its VNode hostnames, one per line,
+// and refreshes its modification time while alive. Files not refreshed
+// within the TTL are considered stale and ignored.
+type FileRegistry struct {
+	dir  string
+	name string
+	ttl  time.Duration
+
+	mu        sync.Mutex
+	hostnames []string
+	stop      chan struct{}
+}
+
+// NewFileRegistry creates a FileRegistry in dir. name identifies this process'
+// seed file and must be unique among the processes sharing dir.
+func NewFileRegistry(dir string, name string, ttl time.Duration) (*FileRegistry, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &FileRegistry{
+		dir:  dir,
+		name: sanitize(name),
+		ttl:  ttl,
+	}, nil
+}
+
+// sanitize turns name into a portable file name.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+func (r *FileRegistry) path() string {
+	return filepath.Join(r.dir, r.name+seedFileSuffix)
+}
+
+// Seeds reads the hostnames of all fresh seed files other than our own.
+func (r *FileRegistry) Seeds() ([]string, error) {
+	entries, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	hostnames := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), seedFileSuffix) {
+			continue
+		}
+		if entry.Name() == r.name+seedFileSuffix {
+			continue
+		}
+		if time.Since(entry.ModTime()) > r.ttl {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(r.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			hostnames = append(hostnames, strings.TrimSpace(line))
+		}
+	}
+
+	r.mu.Lock()
+	own := r.hostnames
+	r.mu.Unlock()
+
+	return excluding(hostnames, own), nil
+}
+
+// Register writes the seed file and keeps refreshing it in the background.
+func (r *FileRegistry) Register(hostnames []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hostnames = hostnames
+	if err := r.write(); err != nil {
+		return err
+	}
+
+	if r.stop == nil {
+		r.stop = make(chan struct{})
+		go r.refresh(r.stop)
+	}
+
+	return nil
+}
+
+// write atomically replaces the seed file.
+func (r *FileRegistry) write() error {
+	tmp := r.path() + ".tmp"
+	data := strings.Join(r.hostnames, "\n") + "\n"
+	if err := ioutil.WriteFile(tmp, []byte(data), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, r.path())
+}
+
+// refresh touches the seed file every third of the TTL until stop is closed.
+func (r *FileRegistry) refresh(stop chan struct{}) {
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			if err := os.Chtimes(r.path(), now, now); err != nil {
+				r.mu.Lock()
+				r.write()
+				r.mu.Unlock()
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Deregister stops refreshing and removes the seed file.
+func (r *FileRegistry) Deregister() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.hostnames = nil
+
+	if err := os.Remove(r.path()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing seed file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package discovery
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// seeds returns the sorted seeds of r.
+func seeds(t *testing.T, r *FileRegistry) []string {
+	t.Helper()
+	hostnames, err := r.Seeds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+func TestFileRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := NewFileRegistry(dir, "node-a:0/8090", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewFileRegistry(dir, "node-b", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Deregister()
+	defer b.Deregister()
+
+	if err := a.Register([]string{"10.0.0.1:8001", "10.0.0.1:8000"}); err != nil {
+		t.Fatal(err)
+	}
+	// b also lists a VNode of its own, which it must not discover.
+	if err := b.Register([]string{"10.0.0.2:8000", "10.0.0.1:8000"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "node-a_0_8090"+seedFileSuffix)); err != nil {
+		t.Errorf("seed file not named after the sanitized name: %v", err)
+	}
+
+	if got, want := seeds(t, a), []string{"10.0.0.2:8000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("a.Seeds() = %q, want %q", got, want)
+	}
+	if got, want := seeds(t, b), []string{"10.0.0.1:8001"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("b.Seeds() = %q, want %q", got, want)
+	}
+
+	// Seed files not refreshed within the TTL are ignored.
+	stale := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(b.path(), stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	if got := seeds(t, a); len(got) != 0 {
+		t.Errorf("a.Seeds() = %q with a stale seed file, want none", got)
+	}
+
+	if err := a.Deregister(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(a.path()); !os.IsNotExist(err) {
+		t.Errorf("seed file left after Deregister: %v", err)
+	}
+	if got := seeds(t, b); len(got) != 0 {
+		t.Errorf("b.Seeds() = %q after a deregistered, want none", got)
+	}
+	if err := a.Deregister(); err != nil {
+		t.Errorf("second Deregister() = %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ServiceName is the DNS-SD service type advertised by Chord nodes.
+	ServiceName = "_chord._tcp.local."
+
+	mdnsGroup = "224.0.0.251:5353"
+	mdnsTTL   = 120
+
+	typePTR = 12
+	typeTXT = 16
+	typeANY = 255
+	classIN = 1
+
+	flagResponse      = 1 << 15
+	flagAuthoritative = 1 << 10
+
+	txtHostKey = "host="
+)
+
+var errMalformed = errors.New("malformed DNS message")
+
+// MDNS discovers ring members on the local network through multicast DNS.
+// Registered processes answer PTR queries for ServiceName with one TXT record
+// listing the hostnames of their VNodes as host=<hostname> strings.
+type MDNS struct {
+	instance string
+	timeout  time.Duration
+
+	mu        sync.Mutex
+	hostnames []string
+	conn      *net.UDPConn
+}
+
+// NewMDNS creates an MDNS provider. instance names this process' service
+// instance, timeout bounds how long Seeds waits for answers.
+func NewMDNS(instance string, timeout time.Duration) *MDNS {
+	label := sanitize(instance)
+	if len(label) > 63 {
+		label = label[:63]
+	}
+
+	return &MDNS{
+		instance: label + "." + ServiceName,
+		timeout:  timeout,
+	}
+}
+
+// Seeds multicasts a PTR query for ServiceName and collects the advertised
+// hostnames from the answers received before the timeout.
+func (m *MDNS) Seeds() ([]string, error) {
+	group, err := net.ResolveUDPAddr("udp4", mdnsGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	query := encodeHeader(nil, 0, 1, 0)
+	query = encodeName(query, ServiceName)
+	query = appendUint16(query, typePTR)
+	query = appendUint16(query, classIN)
+	if _, err := conn.WriteToUDP(query, group); err != nil {
+		return nil, err
+	}
+
+	hostnames := make([]string, 0)
+	buf := make([]byte, 9000)
+	conn.SetReadDeadline(time.Now().Add(m.timeout))
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+
+		flags, _, records, err := parseMessage(buf[:n])
+		if err != nil || flags&flagResponse == 0 {
+			continue
+		}
+		for _, record := range records {
+			if record.rtype == typeTXT && strings.HasSuffix(record.name, ServiceName) {
+				hostnames = append(hostnames, parseTXTHosts(record.data)...)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	own := m.hostnames
+	m.mu.Unlock()
+
+	return excluding(hostnames, own), nil
+}
+
+// Register joins the mDNS multicast group and answers queries for ServiceName.
+func (m *MDNS) Register(hostnames []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hostnames = hostnames
+	if m.conn != nil {
+		return nil
+	}
+
+	group, err := net.ResolveUDPAddr("udp4", mdnsGroup)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return err
+	}
+	m.conn = conn
+
+	go m.serve(conn, group)
+	return nil
+}
+
+// serve answers queries received on conn until it is closed.
+func (m *MDNS) serve(conn *net.UDPConn, group *net.UDPAddr) {
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		flags, questions, _, err := parseMessage(buf[:n])
+		if err != nil || flags&flagResponse != 0 || !asksFor(questions, ServiceName) {
+			continue
+		}
+
+		m.mu.Lock()
+		response := m.response()
+		m.mu.Unlock()
+
+		// Queries from port 5353 are answered on the group, others are
+		// legacy unicast queries answered directly.
+		dst := src
+		if src.Port == group.Port {
+			dst = group
+		}
+		conn.WriteToUDP(response, dst)
+	}
+}
+
+// response builds the answer to a PTR query for ServiceName.
+func (m *MDNS) response() []byte {
+	msg := encodeHeader(nil, flagResponse|flagAuthoritative, 0, 2)
+
+	rdata := encodeName(nil, m.instance)
+	msg = encodeRecord(msg, ServiceName, typePTR, rdata)
+
+	txt := make([]byte, 0)
+	for _, hostname := range m.hostnames {
+		entry := txtHostKey + hostname
+		if len(entry) > 255 {
+			continue
+		}
+		txt = append(txt, byte(len(entry)))
+		txt = append(txt, entry...)
+	}
+	msg = encodeRecord(msg, m.instance, typeTXT, txt)
+
+	return msg
+}
+
+// Deregister leaves the multicast group and stops answering queries.
+func (m *MDNS) Deregister() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hostnames = nil
+	if m.conn == nil {
+		return nil
+	}
+
+	err := m.conn.Close()
+	m.conn = nil
+	return err
+}
+
+// asksFor reports whether questions contain a PTR or ANY question for name.
+func asksFor(questions []dnsQuestion, name string) bool {
+	for _, question := range questions {
+		if strings.EqualFold(question.name, name) && (question.qtype == typePTR || question.qtype == typeANY) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTXTHosts extracts the host=<hostname> strings of TXT record data.
+func parseTXTHosts(data []byte) []string {
+	hostnames := make([]string, 0)
+	for len(data) > 0 {
+		length := int(data[0])
+		if 1+length > len(data) {
+			break
+		}
+		entry := string(data[1 : 1+length])
+		if strings.HasPrefix(entry, txtHostKey) {
+			hostnames = append(hostnames, strings.TrimPrefix(entry, txtHostKey))
+		}
+		data = data[1+length:]
+	}
+
+	return hostnames
+}
+
+type dnsQuestion struct {
+	name  string
+	qtype uint16
+}
+
+type dnsRecord struct {
+	name  string
+	rtype uint16
+	data  []byte
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// encodeHeader appends a DNS header with the given flags and section counts.
+func encodeHeader(b []byte, flags uint16, questions uint16, answers uint16) []byte {
+	b = appendUint16(b, 0)
+	b = appendUint16(b, flags)
+	b = appendUint16(b, questions)
+	b = appendUint16(b, answers)
+	b = appendUint16(b, 0)
+	return appendUint16(b, 0)
+}
+
+// encodeName appends name as uncompressed DNS labels.
+func encodeName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// encodeRecord appends a resource record of class IN.
+func encodeRecord(b []byte, name string, rtype uint16, rdata []byte) []byte {
+	b = encodeName(b, name)
+	b = appendUint16(b, rtype)
+	b = appendUint16(b, classIN)
+	b = appendUint32(b, mdnsTTL)
+	b = appendUint16(b, uint16(len(rdata)))
+	return append(b, rdata...)
+}
+
+// parseMessage decodes the flags, questions and answer records of a DNS message.
+func parseMessage(msg []byte) (uint16, []dnsQuestion, []dnsRecord, error) {
+	if len(msg) < 12 {
+		return 0, nil, nil, errMalformed
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:])
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	ancount := int(binary.BigEndian.Uint16(msg[6:]))
+
+	offset := 12
+	questions := make([]dnsQuestion, 0, qdcount)
+	for i := 0; i < qdcount; i++ {
+		name, next, err := readName(msg, offset)
+		if err != nil || next+4 > len(msg) {
+			return 0, nil, nil, errMalformed
+		}
+		questions = append(questions, dnsQuestion{name: name, qtype: binary.BigEndian.Uint16(msg[next:])})
+		offset = next + 4
+	}
+
+	records := make([]dnsRecord, 0, ancount)
+	for i := 0; i < ancount; i++ {
+		name, next, err := readName(msg, offset)
+		if err != nil || next+10 > len(msg) {
+			return 0, nil, nil, errMalformed
+		}
+		rtype := binary.BigEndian.Uint16(msg[next:])
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		if start+length > len(msg) {
+			return 0, nil, nil, errMalformed
+		}
+		records = append(records, dnsRecord{name: name, rtype: rtype, data: msg[start : start+length]})
+		offset = start + length
+	}
+
+	return flags, questions, records, nil
+}
+
+// readName decodes a possibly compressed name at offset and returns it
+// with the offset following it.
+func readName(msg []byte, offset int) (string, int, error) {
+	labels := make([]string, 0)
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, errMalformed
+		}
+
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(msg) || jumps > 16 {
+				return "", 0, errMalformed
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3FFF)
+			jumps++
+		case length > 63:
+			// 0x40 and 0x80 are reserved label types.
+			return "", 0, errMalformed
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package discovery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseRoundTrip(t *testing.T) {
+	m := NewMDNS("node one:8090", time.Second)
+	m.hostnames = []string{"10.0.0.1:8000", "10.0.0.1:8001", strings.Repeat("a", 255)}
+
+	flags, questions, records, err := parseMessage(m.response())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags&flagResponse == 0 || len(questions) != 0 || len(records) != 2 {
+		t.Fatalf("parsed flags %#x, %d questions, %d records, want a response with 2 records", flags, len(questions), len(records))
+	}
+
+	ptr, txt := records[0], records[1]
+	if ptr.rtype != typePTR || ptr.name != ServiceName {
+		t.Errorf("first record %d %q, want PTR %q", ptr.rtype, ptr.name, ServiceName)
+	}
+	if instance, _, err := readName(ptr.data, 0); err != nil || instance != m.instance {
+		t.Errorf("PTR points to %q, %v, want %q", instance, err, m.instance)
+	}
+	if txt.rtype != typeTXT || txt.name != m.instance {
+		t.Errorf("second record %d %q, want TXT %q", txt.rtype, txt.name, m.instance)
+	}
+
+	// Hostnames too long for a TXT string are left out.
+	if hostnames := parseTXTHosts(txt.data); !reflect.DeepEqual(hostnames, m.hostnames[:2]) {
+		t.Errorf("TXT hosts %q, want %q", hostnames, m.hostnames[:2])
+	}
+}
+
+func TestQueryRoundTrip(t *testing.T) {
+	query := encodeHeader(nil, 0, 1, 0)
+	query = encodeName(query, strings.ToUpper(ServiceName))
+	query = appendUint16(query, typeANY)
+	query = appendUint16(query, classIN)
+
+	flags, questions, _, err := parseMessage(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags&flagResponse != 0 || !asksFor(questions, ServiceName) {
+		t.Errorf("parsed %#x %v, want a query for %s", flags, questions, ServiceName)
+	}
+
+	questions[0].qtype = typeTXT
+	if asksFor(questions, ServiceName) {
+		t.Error("asksFor() accepted a TXT question")
+	}
+}
+
+func TestReadCompressedName(t *testing.T) {
+	msg := encodeHeader(nil, flagResponse, 0, 1)
+	msg = encodeName(msg, ServiceName)
+	// "node" followed by a pointer to the name at offset 12.
+	name := len(msg)
+	msg = append(msg, 4, 'n', 'o', 'd', 'e', 0xC0, 12)
+
+	got, next, err := readName(msg, name)
+	if err != nil || got != "node."+ServiceName || next != len(msg) {
+		t.Errorf("readName() = %q, %d, %v, want %q, %d", got, next, err, "node."+ServiceName, len(msg))
+	}
+}
+
+func TestParseMalformedMessages(t *testing.T) {
+	question := encodeHeader(nil, 0, 1, 0)
+	question = encodeName(question, ServiceName)
+	question = appendUint16(question, typePTR)
+	question = appendUint16(question, classIN)
+
+	answer := encodeRecord(encodeHeader(nil, flagResponse, 0, 1), ServiceName, typeTXT, []byte("\x05host="))
+
+	tests := []struct {
+		name string
+		msg  []byte
+	}{
+		{"short header", make([]byte, 11)},
+		{"missing question", encodeHeader(nil, 0, 1, 0)},
+		{"truncated question", question[:len(question)-1]},
+		{"truncated label", question[:14]},
+		{"reserved label type", append(append(encodeHeader(nil, 0, 1, 0), 0x40), strings.Repeat("a", 64)+string(question[12:])...)},
+		{"pointer loop", append(encodeHeader(nil, 0, 1, 0), 0xC0, 12, 0, typePTR, 0, classIN)},
+		{"truncated pointer", append(encodeHeader(nil, 0, 1, 0), 0xC0)},
+		{"truncated record", answer[:len(answer)-1]},
+		{"missing record", encodeHeader(nil, flagResponse, 0, 2)},
+	}
+	for _, test := range tests {
+		if _, _, _, err := parseMessage(test.msg); err != errMalformed {
+			t.Errorf("%s: parseMessage() error = %v, want errMalformed", test.name, err)
+		}
+	}
+}
+
+func TestParseTXTHosts(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want []string
+	}{
+		{[]byte("\x0bhost=a:8000\x00\x07txtvers\x0bhost=b:8000"), []string{"a:8000", "b:8000"}},
+		{[]byte("\x0bhost=a:8000\x20host=cut"), []string{"a:8000"}},
+		{nil, []string{}},
+	}
+	for _, test := range tests {
+		if got := parseTXTHosts(test.data); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseTXTHosts(%q) = %q, want %q", test.data, got, test.want)
+		}
+	}
+}
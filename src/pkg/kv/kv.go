@@ -0,0 +1,427 @@
+package kv
+
+// Key-value storage on a Chord ring.
+// Every key is stored by the VNode owning its ID, in a storage engine of its
+// own, and by the next VNodes of its successor list if replicated. A VNode
+// hands the keys it no longer owns to a new predecessor, and all of its keys
+// to its successor when it leaves the ring. Values are versioned, see
+// Versions, and handed over versions are merged with the stored ones.
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+	Storage "github.com/arush15june/chord-golang/src/pkg/storage"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// ServiceName is the name of the RPC service serving the keys of a VNode.
+const ServiceName = "KV"
+
+// KeysStat is the number of keys stored by a VNode.
+const KeysStat = "keys"
+
+// ErrNotFound is returned for keys which are not stored.
+var ErrNotFound = errors.New("kv: key not found")
+
+// ErrConflict is returned by Get for keys with concurrent values, read them with GetSiblings.
+var ErrConflict = errors.New("kv: key has conflicting values")
+
+// ErrDetached is returned by operations on a Store not attached to a Ring.
+var ErrDetached = errors.New("kv: store is not attached to a ring")
+
+// Store stores the keys replicated by the local VNodes of a Ring and reaches
+// the replicas of other keys over RPC. It is the Delegate of the Ring,
+// moving keys when the key ranges of the local VNodes change.
+type Store struct {
+	Chord.NopDelegate
+
+	ring *Chord.Ring
+	log  *Logging.Logger
+	open Storage.Opener
+
+	// replicas is the number of VNodes storing every key.
+	replicas int
+	// readLevel and writeLevel are the consistencies used by default.
+	readLevel  Consistency
+	writeLevel Consistency
+	// pruneInterval is the period between drops of keys the local VNodes no longer replicate.
+	pruneInterval time.Duration
+
+	mu sync.RWMutex
+	// engines maps VNode hostnames to the engines storing their keys.
+	engines map[string]Storage.Engine
+
+	// writeMu serializes reading and rewriting the versions of keys.
+	writeMu sync.Mutex
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithStorage sets the Opener of the storage engines of the VNodes, in memory by default.
+func WithStorage(open Storage.Opener) Option {
+	return func(s *Store) {
+		s.open = open
+	}
+}
+
+// WithReplicas sets the number of VNodes storing every key, 1 by default.
+// The successor lists of the Ring must hold at least n-1 VNodes.
+func WithReplicas(n int) Option {
+	return func(s *Store) {
+		s.replicas = n
+	}
+}
+
+// WithConsistency sets the consistency of reads and writes not choosing
+// their own, Quorum by default.
+func WithConsistency(read Consistency, write Consistency) Option {
+	return func(s *Store) {
+		s.readLevel = read
+		s.writeLevel = write
+	}
+}
+
+// WithPruneInterval sets the period between drops of the keys the local VNodes
+// no longer replicate, one minute by default. It only applies with replication.
+func WithPruneInterval(interval time.Duration) Option {
+	return func(s *Store) {
+		s.pruneInterval = interval
+	}
+}
+
+// New creates a Store. Pass it to the Ring with Chord.WithDelegate and
+// attach it to the Ring once created.
+func New(opts ...Option) *Store {
+	s := &Store{
+		log:     Logging.NewRegistry(ioutil.Discard, Logging.LogfmtFormat, Logging.ErrorLevel).Logger("kv"),
+		open:    Storage.OpenMemory,
+		engines: make(map[string]Storage.Engine),
+		stop:    make(chan struct{}),
+
+		replicas:      1,
+		readLevel:     Quorum,
+		writeLevel:    Quorum,
+		pruneInterval: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.replicas < 1 {
+		s.replicas = 1
+	}
+	s.readLevel = level(s.readLevel, Quorum)
+	s.writeLevel = level(s.writeLevel, Quorum)
+	return s
+}
+
+// Attach opens the storage engines of the VNodes of ring and registers the
+// RPC service of the Store on every VNode.
+func (s *Store) Attach(ring *Chord.Ring) error {
+	s.ring = ring
+	s.log = ring.Logs().Logger("kv")
+
+	for _, vnode := range ring.VNodes() {
+		engine, err := s.open(vnode.ID())
+		if err != nil {
+			s.Close()
+			return err
+		}
+		s.mu.Lock()
+		s.engines[vnode.Hostname()] = engine
+		s.mu.Unlock()
+		s.log.Info("Opened storage", "vnode", vnode.Hostname(), "keys", engine.Len())
+	}
+
+	if err := ring.RegisterService(ServiceName, func(vnode *Chord.LocalVNode) interface{} {
+		return &Service{store: s, hostname: vnode.Hostname()}
+	}); err != nil {
+		return err
+	}
+
+	if s.replicas > 1 && s.pruneInterval > 0 {
+		go s.pruneRoutine(s.pruneInterval)
+	}
+	return nil
+}
+
+// Close stops pruning and closes the storage engines of the VNodes.
+func (s *Store) Close() error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for hostname, engine := range s.engines {
+		if err := engine.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.engines, hostname)
+	}
+	return firstErr
+}
+
+// engine returns the storage engine of the local VNode at hostname.
+func (s *Store) engine(hostname string) (Storage.Engine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	engine, ok := s.engines[hostname]
+	if !ok {
+		return nil, ErrDetached
+	}
+	return engine, nil
+}
+
+// Stats returns the number of keys stored by vnode, for Chord.WithStatsSource.
+func (s *Store) Stats(vnode *Chord.LocalVNode) Chord.Stats {
+	engine, err := s.engine(vnode.Hostname())
+	if err != nil {
+		return Chord.Stats{KeysStat: 0}
+	}
+	return Chord.Stats{KeysStat: float64(engine.Len())}
+}
+
+// Siblings are the values of a key written concurrently and the causal
+// context of a write replacing them.
+type Siblings struct {
+	Values  [][]byte
+	Context VClock
+}
+
+// Get returns the value of key read with consistency r, or ErrConflict if it has siblings.
+func (s *Store) Get(key string, r Consistency) ([]byte, error) {
+	siblings, err := s.GetSiblings(key, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(siblings.Values) > 1 {
+		return nil, ErrConflict
+	}
+	return siblings.Values[0], nil
+}
+
+// GetSiblings returns the values of key read with consistency r and their
+// causal context. Write with the context to replace all of them.
+func (s *Store) GetSiblings(key string, r Consistency) (*Siblings, error) {
+	versions, err := s.readReplicas(key, r)
+	if err != nil {
+		return nil, err
+	}
+
+	values := versions.Values()
+	if len(values) == 0 {
+		return nil, ErrNotFound
+	}
+	return &Siblings{Values: values, Context: versions.Context()}, nil
+}
+
+// Put sets the value of key with consistency w, replacing all of its values.
+// The values are read with consistency w first.
+func (s *Store) Put(key string, value []byte, w Consistency) error {
+	versions, err := s.readReplicas(key, w)
+	if err != nil {
+		return err
+	}
+	_, err = s.writeReplicas(key, value, false, versions.Context(), w)
+	return err
+}
+
+// PutContext sets the value of key with consistency w, replacing the values
+// seen in context, as returned by GetSiblings. Values written concurrently
+// remain siblings. A nil context replaces nothing.
+func (s *Store) PutContext(key string, value []byte, context VClock, w Consistency) error {
+	if context == nil {
+		context = VClock{}
+	}
+	_, err := s.writeReplicas(key, value, false, context, w)
+	return err
+}
+
+// Delete removes key with consistency w. The values are read with consistency w first.
+func (s *Store) Delete(key string, w Consistency) error {
+	versions, err := s.readReplicas(key, w)
+	if err != nil {
+		return err
+	}
+	if len(versions.Values()) == 0 {
+		return ErrNotFound
+	}
+	_, err = s.writeReplicas(key, nil, true, versions.Context(), w)
+	return err
+}
+
+// DeleteContext removes the values of key seen in context with consistency w.
+// Values written concurrently remain.
+func (s *Store) DeleteContext(key string, context VClock, w Consistency) error {
+	if context == nil {
+		context = VClock{}
+	}
+	found, err := s.writeReplicas(key, nil, true, context, w)
+	if err == nil && !found && len(context) == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// read returns the versions of key stored by hostname.
+func (s *Store) read(hostname string, key string) (Versions, error) {
+	engine, err := s.engine(hostname)
+	if err != nil {
+		return nil, err
+	}
+	return readVersions(engine, key)
+}
+
+// readVersions returns the versions of key stored in engine.
+func readVersions(engine Storage.Engine, key string) (Versions, error) {
+	data, ok, err := engine.Get(key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return decodeVersions(data)
+}
+
+// apply writes value, or a tombstone if deleted, as a version of key on
+// hostname having read context. It returns whether key had values and the
+// resulting versions, nil if a tombstone was not written as nothing was deleted.
+func (s *Store) apply(hostname string, key string, value []byte, deleted bool, context VClock) (bool, Versions, error) {
+	engine, err := s.engine(hostname)
+	if err != nil {
+		return false, nil, err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	versions, err := readVersions(engine, key)
+	if err != nil {
+		return false, nil, err
+	}
+	found := len(versions.Values()) > 0
+	if deleted && len(versions) == 0 && len(context) == 0 {
+		// Nothing to delete, a tombstone would only take space.
+		return false, nil, nil
+	}
+
+	versions = versions.Write(hostname, value, deleted, context)
+	return found, versions, engine.Put(Storage.NewItem(key, encodeVersions(versions)))
+}
+
+// merge merges versions of keys handed over by other VNodes into the versions stored by hostname.
+func (s *Store) merge(hostname string, items map[string][]byte) error {
+	engine, err := s.engine(hostname)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	merged := make([]Storage.Item, 0, len(items))
+	for key, data := range items {
+		incoming, err := decodeVersions(data)
+		if err != nil {
+			s.log.Warn("Dropping malformed versions", "vnode", hostname, "key", key, "err", err)
+			continue
+		}
+		versions, err := readVersions(engine, key)
+		if err != nil {
+			return err
+		}
+		merged = append(merged, Storage.NewItem(key, encodeVersions(versions.Merge(incoming))))
+	}
+	return engine.Put(merged...)
+}
+
+// mergeInto merges the versions of keys in items into the VNode at hostname.
+func (s *Store) mergeInto(hostname string, items map[string][]byte) error {
+	if s.ring.LocalVNode(hostname) != nil {
+		return s.merge(hostname, items)
+	}
+	return s.ring.Transport().Remote(hostname).Call(ServiceName+".Transfer", &TransferArgs{Items: items}, &TransferReply{})
+}
+
+// discard deletes the keys in items from hostname unless their versions
+// changed since items were read, and returns the number of keys deleted.
+func (s *Store) discard(hostname string, items map[string][]byte) (int, error) {
+	engine, err := s.engine(hostname)
+	if err != nil {
+		return 0, err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	keys := make([]string, 0, len(items))
+	for key, data := range items {
+		if stored, ok, err := engine.Get(key); err == nil && ok && bytes.Equal(stored, data) {
+			keys = append(keys, key)
+		}
+	}
+	return engine.Delete(keys...)
+}
+
+// transfer moves the keys of hostname with IDs in the ring interval (from, to] to target.
+func (s *Store) transfer(hostname string, target VNode.VNodeProtocol, from uint64, to uint64) {
+	engine, err := s.engine(hostname)
+	if err != nil {
+		return
+	}
+
+	items := make(map[string][]byte)
+	engine.Range(from, to, func(item Storage.Item) bool {
+		items[item.Key] = item.Value
+		return true
+	})
+	if len(items) == 0 {
+		return
+	}
+
+	if err := s.mergeInto(target.Hostname(), items); err != nil {
+		s.log.Warn("Failed to transfer keys", "vnode", hostname, "target", target.Hostname(), "keys", len(items), "err", err)
+		return
+	}
+	if _, err := s.discard(hostname, items); err != nil {
+		s.log.Warn("Failed to delete transferred keys", "vnode", hostname, "keys", len(items), "err", err)
+	}
+
+	s.log.Info("Transferred keys", "vnode", hostname, "target", target.Hostname(), "keys", len(items))
+}
+
+// NewPredecessor hands the keys outside (predecessor, local] to the new
+// predecessor. With replication, it hands over the keys the predecessor
+// replicates and drops the ones local no longer replicates, see rebalance.
+func (s *Store) NewPredecessor(local *Chord.LocalVNode, previous VNode.VNodeProtocol, predecessor VNode.VNodeProtocol) {
+	if s.ring == nil || predecessor == nil || predecessor.ID() == local.ID() {
+		return
+	}
+
+	if s.replicas > 1 {
+		go s.rebalance(local, predecessor)
+		return
+	}
+	// The keys outside (predecessor, local] are the keys in (local, predecessor].
+	go s.transfer(local.Hostname(), predecessor, local.ID(), predecessor.ID())
+}
+
+// Leaving hands all keys of local to its successor.
+func (s *Store) Leaving(local *Chord.LocalVNode, predecessor VNode.VNodeProtocol, successor VNode.VNodeProtocol) {
+	if s.ring == nil || successor == nil || successor.ID() == local.ID() {
+		return
+	}
+
+	s.transfer(local.Hostname(), successor, local.ID(), local.ID())
+}
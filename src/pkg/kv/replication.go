@@ -0,0 +1,500 @@
+package kv
+
+// Replication.
+// A key is stored by the N replicas of its preference list: the VNode owning
+// it and the next N-1 VNodes of its successor list. Reads ask all replicas
+// and return once R answered, merging their versions. Writes are applied by
+// the first reachable replica, which versions them, and the resulting
+// versions are merged into the others until W acknowledged. Replicas which
+// answered a read with versions differing from the merged ones are repaired
+// in the background, once all replicas answered. A VNode gaining a new
+// predecessor hands it the keys it now replicates and drops the keys it no
+// longer replicates itself. VNodes pushed out of preference lists further
+// down the ring drop the keys periodically.
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+	Storage "github.com/arush15june/chord-golang/src/pkg/storage"
+	Util "github.com/arush15june/chord-golang/src/pkg/util"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// Consistency is the number of replicas a read or write waits for.
+type Consistency int
+
+const (
+	// DefaultConsistency is the consistency the Store is configured with.
+	DefaultConsistency Consistency = iota
+	// One waits for a single replica.
+	One
+	// Quorum waits for a majority of the replicas.
+	Quorum
+	// All waits for every replica.
+	All
+)
+
+var consistencyNames = map[Consistency]string{
+	DefaultConsistency: "default",
+	One:                "one",
+	Quorum:             "quorum",
+	All:                "all",
+}
+
+func (c Consistency) String() string {
+	return consistencyNames[c]
+}
+
+// ParseConsistency parses "one", "quorum" or "all", case insensitively.
+func ParseConsistency(name string) (Consistency, error) {
+	for c, cName := range consistencyNames {
+		if c != DefaultConsistency && strings.EqualFold(cName, name) {
+			return c, nil
+		}
+	}
+	return DefaultConsistency, fmt.Errorf("unknown consistency %q, use one, quorum or all", name)
+}
+
+// required returns the number of the n replicas waited for.
+func (c Consistency) required(n int) int {
+	switch c {
+	case One:
+		return 1
+	case All:
+		return n
+	}
+	return n/2 + 1
+}
+
+// ErrUnavailable is returned when fewer replicas answered than the consistency requires.
+var ErrUnavailable = errors.New("kv: not enough replicas available")
+
+// preferenceList returns the hostnames of the replicas of key, the owner first,
+// and the number of replicas of the key, see replicasOf.
+// It is shorter than the replication factor if the ring has fewer VNodes, or
+// if successors of the owner are unknown while the ring changes.
+func (s *Store) preferenceList(key string) ([]string, int, error) {
+	if s.ring == nil {
+		return nil, 0, ErrDetached
+	}
+
+	owner, err := s.ring.Lookup(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.replicasOf(owner)
+}
+
+// replicasOf returns the hostnames of the replicas of the keys owned by the
+// VNode at owner, the owner first, and the number of replicas of the keys:
+// the replication factor, unless the successors of the owner lead back around
+// to it, proving the ring holds fewer VNodes. A successor list which is
+// merely not filled yet proves nothing, replicas missing from it count as failed.
+func (s *Store) replicasOf(owner string) ([]string, int, error) {
+	hostnames := []string{owner}
+	if s.replicas == 1 {
+		return hostnames, 1, nil
+	}
+
+	var successors []VNode.VNodeProtocol
+	var err error
+	if local := s.ring.LocalVNode(owner); local != nil {
+		if successors, err = local.FindSuccessors(s.replicas - 1); err != nil {
+			return nil, 0, err
+		}
+	} else if successors, err = s.ring.Transport().Remote(owner).FindSuccessors(s.replicas - 1); err != nil {
+		// The ring has not noticed the owner failing yet, find its successors
+		// another way. The owner stays in the list as a failed replica.
+		s.log.Warn("Failed to find successors of owner", "owner", owner, "err", err)
+		successors = s.successorsOf(owner)
+	}
+
+	wrapped := false
+	seen := map[string]bool{owner: true}
+	for _, successor := range successors {
+		if len(hostnames) == s.replicas {
+			break
+		}
+		if successor == nil {
+			continue
+		}
+		if hostname := successor.Hostname(); !seen[hostname] {
+			seen[hostname] = true
+			hostnames = append(hostnames, hostname)
+		} else {
+			wrapped = true
+		}
+	}
+
+	// Successor lists end before the VNode itself, a short list is complete
+	// if its last VNode is followed by the owner.
+	if !wrapped && len(hostnames) > 1 && len(hostnames) < s.replicas {
+		wrapped = s.successorOf(hostnames[len(hostnames)-1]) == owner
+	}
+	if wrapped {
+		return hostnames, len(hostnames), nil
+	}
+	return hostnames, s.replicas, nil
+}
+
+// successorOf returns the hostname of the successor of the VNode at hostname,
+// empty if it is unknown.
+func (s *Store) successorOf(hostname string) string {
+	var vnode VNode.VNodeProtocol = s.ring.Transport().Remote(hostname)
+	if local := s.ring.LocalVNode(hostname); local != nil {
+		vnode = local
+	}
+
+	successors, err := vnode.FindSuccessors(1)
+	if err != nil || len(successors) == 0 || successors[0] == nil {
+		return ""
+	}
+	return successors[0].Hostname()
+}
+
+// successorsOf returns successors of the unreachable VNode at hostname,
+// starting with a VNode found following it locally: a local VNode it
+// precedes, or the VNode after it in the successor list of a local VNode.
+func (s *Store) successorsOf(hostname string) []VNode.VNodeProtocol {
+	var next VNode.VNodeProtocol
+	for _, vnode := range s.ring.VNodes() {
+		if predecessor, _ := vnode.GetPredecessor(); predecessor != nil && predecessor.Hostname() == hostname {
+			next = vnode
+			break
+		}
+		list, _ := vnode.FindSuccessors(len(s.ring.VNodes()) + s.replicas)
+		for i, successor := range list {
+			if successor != nil && successor.Hostname() == hostname && i+1 < len(list) && list[i+1] != nil {
+				next = list[i+1]
+				break
+			}
+		}
+		if next != nil {
+			break
+		}
+	}
+	if next == nil {
+		return nil
+	}
+
+	successors := []VNode.VNodeProtocol{next}
+	if s.replicas > 2 {
+		more, err := next.FindSuccessors(s.replicas - 2)
+		if err != nil {
+			s.log.Warn("Failed to find successors", "vnode", next.Hostname(), "err", err)
+		}
+		successors = append(successors, more...)
+	}
+	return successors
+}
+
+// level returns c, or fallback if c is the default.
+func level(c Consistency, fallback Consistency) Consistency {
+	if c == DefaultConsistency {
+		return fallback
+	}
+	return c
+}
+
+// readReplica returns the versions of key stored by the replica at hostname.
+func (s *Store) readReplica(hostname string, key string) (Versions, error) {
+	if s.ring.LocalVNode(hostname) != nil {
+		return s.read(hostname, key)
+	}
+
+	reply := &GetReply{}
+	err := s.ring.Transport().Remote(hostname).Call(ServiceName+".Get", &GetArgs{Key: key}, reply)
+	return reply.Versions, err
+}
+
+// applyReplica writes a version of key on the replica at hostname, see apply.
+func (s *Store) applyReplica(hostname string, key string, value []byte, deleted bool, context VClock) (bool, Versions, error) {
+	if s.ring.LocalVNode(hostname) != nil {
+		return s.apply(hostname, key, value, deleted, context)
+	}
+
+	remote := s.ring.Transport().Remote(hostname)
+	if deleted {
+		reply := &DeleteReply{}
+		err := remote.Call(ServiceName+".Delete", &DeleteArgs{Key: key, Context: context}, reply)
+		return reply.Found, reply.Versions, err
+	}
+	reply := &PutReply{}
+	err := remote.Call(ServiceName+".Put", &PutArgs{Key: key, Value: value, Context: context}, reply)
+	return reply.Found, reply.Versions, err
+}
+
+// mergeReplica merges versions of key into the replica at hostname.
+func (s *Store) mergeReplica(hostname string, key string, versions Versions) error {
+	return s.mergeInto(hostname, map[string][]byte{key: encodeVersions(versions)})
+}
+
+// replicaRead is the answer of a replica to a read.
+type replicaRead struct {
+	hostname string
+	versions Versions
+	err      error
+}
+
+// readReplicas reads key from its replicas and returns the merged versions
+// once the consistency is met. Replicas missing from a short preference list
+// count as failed. Divergent replicas are repaired in the background.
+func (s *Store) readReplicas(key string, c Consistency) (Versions, error) {
+	hostnames, replicas, err := s.preferenceList(key)
+	if err != nil {
+		return nil, err
+	}
+	required := level(c, s.readLevel).required(replicas)
+
+	answers := make(chan replicaRead, len(hostnames))
+	for _, hostname := range hostnames {
+		go func(hostname string) {
+			versions, err := s.readReplica(hostname, key)
+			answers <- replicaRead{hostname: hostname, versions: versions, err: err}
+		}(hostname)
+	}
+
+	var merged Versions
+	received := make([]replicaRead, 0, len(hostnames))
+	succeeded := 0
+	for len(received) < len(hostnames) && succeeded < required {
+		answer := <-answers
+		received = append(received, answer)
+		if answer.err != nil {
+			s.log.Warn("Failed to read replica", "key", key, "replica", answer.hostname, "err", answer.err)
+			continue
+		}
+		succeeded++
+		merged = merged.Merge(answer.versions)
+	}
+	go s.repair(key, received, len(hostnames)-len(received), answers)
+
+	if succeeded < required {
+		return nil, ErrUnavailable
+	}
+	return merged, nil
+}
+
+// repair waits for the pending answers to a read and merges the versions of
+// all answers into the replicas which answered with different ones.
+func (s *Store) repair(key string, received []replicaRead, pending int, answers chan replicaRead) {
+	for ; pending > 0; pending-- {
+		received = append(received, <-answers)
+	}
+
+	var merged Versions
+	for _, answer := range received {
+		if answer.err == nil {
+			merged = merged.Merge(answer.versions)
+		}
+	}
+	for _, answer := range received {
+		if answer.err != nil || answer.versions.Equal(merged) {
+			continue
+		}
+		if err := s.mergeReplica(answer.hostname, key, merged); err != nil {
+			s.log.Warn("Failed to repair replica", "key", key, "replica", answer.hostname, "err", err)
+			continue
+		}
+		s.log.Info("Repaired replica", "key", key, "replica", answer.hostname)
+	}
+}
+
+// writeReplicas writes value, or a tombstone if deleted, having read context
+// and returns once the consistency is met. The first reachable replica
+// applies the write, the others merge its result. Replicas missing from a
+// short preference list count as failed. It reports whether the applying
+// replica had values of key.
+func (s *Store) writeReplicas(key string, value []byte, deleted bool, context VClock, c Consistency) (bool, error) {
+	hostnames, replicas, err := s.preferenceList(key)
+	if err != nil {
+		return false, err
+	}
+	required := level(c, s.writeLevel).required(replicas)
+
+	var found bool
+	var versions Versions
+	applied := -1
+	for i, hostname := range hostnames {
+		if found, versions, err = s.applyReplica(hostname, key, value, deleted, context); err == nil {
+			applied = i
+			break
+		}
+		s.log.Warn("Failed to write replica", "key", key, "replica", hostname, "err", err)
+	}
+	if applied < 0 {
+		return false, ErrUnavailable
+	}
+	if versions == nil {
+		// Nothing was written, see apply.
+		return found, nil
+	}
+
+	others := make([]string, 0, len(hostnames)-1)
+	others = append(append(others, hostnames[:applied]...), hostnames[applied+1:]...)
+	acks := make(chan error, len(others))
+	for _, hostname := range others {
+		go func(hostname string) {
+			err := s.mergeReplica(hostname, key, versions)
+			if err != nil {
+				s.log.Warn("Failed to write replica", "key", key, "replica", hostname, "err", err)
+			}
+			acks <- err
+		}(hostname)
+	}
+
+	succeeded := 1
+	for i := 0; i < len(others) && succeeded < required; i++ {
+		if err := <-acks; err == nil {
+			succeeded++
+		}
+	}
+	if succeeded < required {
+		return found, ErrUnavailable
+	}
+	return found, nil
+}
+
+// withPredecessor returns the preference list hostnames, computed before the
+// ring learned of predecessor joining right before local, as it is once
+// predecessor joined: predecessor precedes local and may push the last
+// replica out.
+func withPredecessor(hostnames []string, local string, predecessor string, replicas int) []string {
+	at := -1
+	for i, hostname := range hostnames {
+		if hostname == local {
+			at = i
+		}
+	}
+	if at < 0 {
+		return hostnames
+	}
+
+	joined := make([]string, 0, len(hostnames)+1)
+	joined = append(append(append(joined, hostnames[:at]...), predecessor), hostnames[at:]...)
+	if len(joined) > replicas {
+		joined = joined[:replicas]
+	}
+	return joined
+}
+
+// contains reports whether hostnames contains hostname.
+func contains(hostnames []string, hostname string) bool {
+	for _, h := range hostnames {
+		if h == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+// rebalance hands the keys of local to predecessor, which joined right before
+// it, if predecessor replicates them, and drops the keys local no longer
+// replicates once all their replicas merged them. Without a predecessor it
+// only drops keys. Keys are grouped by owner, the keys of an owner share
+// their preference list.
+func (s *Store) rebalance(local *Chord.LocalVNode, predecessor VNode.VNodeProtocol) {
+	engine, err := s.engine(local.Hostname())
+	if err != nil {
+		return
+	}
+
+	var items []Storage.Item
+	engine.Range(local.ID(), local.ID(), func(item Storage.Item) bool {
+		items = append(items, item)
+		return true
+	})
+
+	handed, dropped := 0, 0
+	for start := 0; start < len(items); {
+		first := items[start]
+		owner, err := local.FindSuccessor(first.ID)
+		if err == nil && owner == nil {
+			err = errors.New("no owner found")
+		}
+		var hostnames []string
+		if err == nil {
+			hostnames, _, err = s.replicasOf(owner.Hostname())
+		}
+		if err != nil {
+			s.log.Warn("Failed to find replicas, keeping keys", "vnode", local.Hostname(), "key", first.Key, "err", err)
+			return
+		}
+
+		last := owner.ID()
+		if predecessor != nil {
+			// The keys up to the predecessor are its own once the ring learned of it.
+			if Util.IsBetweenID(predecessor.ID(), first.ID-1, last) {
+				last = predecessor.ID()
+			}
+			// Unless the ring already knows the predecessor, insert it. It does
+			// not replicate the keys following it, which local owns.
+			ownedByLocal := hostnames[0] == local.Hostname() && Util.IsBetweenID(first.ID, predecessor.ID(), local.ID())
+			if !contains(hostnames, predecessor.Hostname()) && !ownedByLocal {
+				hostnames = withPredecessor(hostnames, local.Hostname(), predecessor.Hostname(), s.replicas)
+			}
+		}
+
+		group := make(map[string][]byte)
+		for ; start < len(items) && Util.IsBetweenID(items[start].ID, first.ID-1, last); start++ {
+			group[items[start].Key] = items[start].Value
+		}
+
+		// Local hands the keys to the predecessor if it replicates them, or to
+		// all replicas before dropping them if local does not.
+		keep := contains(hostnames, local.Hostname())
+		var targets []string
+		if !keep {
+			targets = hostnames
+		} else if predecessor != nil && contains(hostnames, predecessor.Hostname()) {
+			targets = []string{predecessor.Hostname()}
+		}
+
+		merged := true
+		for _, target := range targets {
+			if err := s.mergeInto(target, group); err != nil {
+				s.log.Warn("Failed to hand over keys", "vnode", local.Hostname(), "target", target, "keys", len(group), "err", err)
+				merged = false
+			} else if predecessor != nil && target == predecessor.Hostname() {
+				handed += len(group)
+			}
+		}
+		if keep || !merged {
+			continue
+		}
+		n, err := s.discard(local.Hostname(), group)
+		if err != nil {
+			s.log.Warn("Failed to drop keys", "vnode", local.Hostname(), "keys", len(group), "err", err)
+		}
+		dropped += n
+	}
+
+	if handed > 0 || dropped > 0 {
+		s.log.Info("Rebalanced keys", "vnode", local.Hostname(), "handed", handed, "dropped", dropped)
+	}
+}
+
+// pruneRoutine drops the keys the local VNodes no longer replicate every
+// interval, until the Store is closed. Replicas leave the preference lists of
+// keys when VNodes join before them, without being notified.
+func (s *Store) pruneRoutine(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !s.ring.Ready() {
+				continue
+			}
+			for _, vnode := range s.ring.VNodes() {
+				s.rebalance(vnode, nil)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package kv
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+)
+
+// ringOptions are the options of the rings of the tests, converging quickly.
+func ringOptions(opts ...Chord.Option) []Chord.Option {
+	return append([]Chord.Option{
+		Chord.WithHostname("127.0.0.1:0"),
+		Chord.WithSuccessors(2),
+		Chord.WithStabilizeInterval(20*time.Millisecond, 50*time.Millisecond),
+		Chord.WithFixFingerInterval(10 * time.Millisecond),
+		Chord.WithMaxFixFingerInterval(50 * time.Millisecond),
+		Chord.WithCallTimeout(time.Second),
+	}, opts...)
+}
+
+// waitForSuccessors waits until every VNode of rings has n successors other
+// than itself.
+func waitForSuccessors(t *testing.T, n int, rings ...*Chord.Ring) {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		complete := true
+		for _, ring := range rings {
+			for _, vnode := range ring.VNodes() {
+				successors, _ := vnode.FindSuccessors(n)
+				others := 0
+				for _, successor := range successors {
+					if successor != nil && successor.Hostname() != vnode.Hostname() {
+						others++
+					}
+				}
+				if others < n {
+					complete = false
+				}
+			}
+		}
+		if complete {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("successor lists did not fill up to %d VNodes", n)
+}
+
+func TestConsistencyWithUnreachableReplica(t *testing.T) {
+	store := New(WithReplicas(3))
+	ring, err := Chord.New(ringOptions(Chord.WithVNodes(2), Chord.WithDelegate(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Attach(ring); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Create(); err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Leave()
+
+	// The third VNode runs no store, every key has a replica failing all requests.
+	unreachable, err := Chord.New(ringOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unreachable.Join(ring.Hostnames()[0]); err != nil {
+		t.Fatal(err)
+	}
+	defer unreachable.Leave()
+	waitForSuccessors(t, 2, ring, unreachable)
+
+	tests := []struct {
+		c       Consistency
+		wantErr error
+	}{
+		{One, nil},
+		{Quorum, nil},
+		{All, ErrUnavailable},
+	}
+	for _, test := range tests {
+		for i := 0; i < 10; i++ {
+			key := fmt.Sprintf("%s-%d", test.c, i)
+			if err := store.Put(key, []byte(key), test.c); err != test.wantErr {
+				t.Errorf("Put(%q, %s) error = %v, want %v", key, test.c, err, test.wantErr)
+			}
+			if test.c != Quorum {
+				continue
+			}
+
+			// Quorum reads overlap quorum writes, the value must be read back.
+			value, err := store.Get(key, Quorum)
+			if err != nil || string(value) != key {
+				t.Errorf("Get(%q, quorum) = %q, %v, want %q", key, value, err, key)
+			}
+			if _, err := store.Get(key, All); err != ErrUnavailable {
+				t.Errorf("Get(%q, all) error = %v, want %v", key, err, ErrUnavailable)
+			}
+		}
+	}
+}
+
+func TestConsistencyOnSmallRing(t *testing.T) {
+	// Two VNodes hold all replicas of a ring smaller than the replication factor.
+	store := New(WithReplicas(3))
+	ring, err := Chord.New(ringOptions(Chord.WithVNodes(2), Chord.WithDelegate(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Attach(ring); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Create(); err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Leave()
+	waitForSuccessors(t, 1, ring)
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if err := store.Put(key, []byte(key), All); err != nil {
+			t.Errorf("Put(%q, all) error = %v", key, err)
+		}
+		if value, err := store.Get(key, All); err != nil || string(value) != key {
+			t.Errorf("Get(%q, all) = %q, %v, want %q", key, value, err, key)
+		}
+	}
+}
+
+// closedRing reports whether following the successors of every VNode of ring
+// visits all of its VNodes before returning to it.
+func closedRing(ring *Chord.Ring) bool {
+	vnodes := ring.VNodes()
+	for _, vnode := range vnodes {
+		hostname := vnode.Hostname()
+		for i := 0; i < len(vnodes); i++ {
+			successors, _ := ring.LocalVNode(hostname).FindSuccessors(1)
+			if len(successors) == 0 || successors[0] == nil {
+				return false
+			}
+			hostname = successors[0].Hostname()
+			if (hostname == vnode.Hostname()) != (i == len(vnodes)-1) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestReplicaCountWithShortSuccessorLists(t *testing.T) {
+	// Successor lists of a single VNode are shorter than the preference lists
+	// of 3 replicas, only a ring of 2 VNodes proves to be smaller.
+	tests := []struct {
+		vnodes  int
+		want    int
+		wantErr error
+	}{
+		{2, 2, nil},
+		{3, 3, ErrUnavailable},
+	}
+	for _, test := range tests {
+		store := New(WithReplicas(3))
+		ring, err := Chord.New(ringOptions(Chord.WithVNodes(test.vnodes), Chord.WithSuccessors(1), Chord.WithDelegate(store))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Attach(ring); err != nil {
+			t.Fatal(err)
+		}
+		if err := ring.Create(); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(10 * time.Second)
+		for !closedRing(ring) && time.Now().Before(deadline) {
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		for _, hostname := range ring.Hostnames() {
+			hostnames, replicas, err := store.replicasOf(hostname)
+			if err != nil || len(hostnames) != 2 || replicas != test.want {
+				t.Errorf("%d VNodes: replicasOf(%s) = %q, %d, %v, want 2 hostnames of %d replicas", test.vnodes, hostname, hostnames, replicas, err, test.want)
+			}
+		}
+		if err := store.Put("key", []byte("value"), All); err != test.wantErr {
+			t.Errorf("%d VNodes: Put(all) error = %v, want %v", test.vnodes, err, test.wantErr)
+		}
+		if err := store.Put("key", []byte("value"), Quorum); err != nil {
+			t.Errorf("%d VNodes: Put(quorum) error = %v", test.vnodes, err)
+		}
+		ring.Leave()
+	}
+}
+
+// copies returns the number of keys stored by the VNodes of stores.
+func copies(stores ...*Store) int {
+	n := 0
+	for _, store := range stores {
+		store.mu.RLock()
+		for _, engine := range store.engines {
+			n += engine.Len()
+		}
+		store.mu.RUnlock()
+	}
+	return n
+}
+
+func TestJoiningVNodeTakesOverReplicas(t *testing.T) {
+	const keys = 40
+	store := New(WithReplicas(2), WithPruneInterval(100*time.Millisecond))
+	ring, err := Chord.New(ringOptions(Chord.WithVNodes(3), Chord.WithDelegate(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Attach(ring); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Create(); err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Leave()
+	waitForSuccessors(t, 2, ring)
+
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if err := store.Put(key, []byte(key), All); err != nil {
+			t.Fatalf("Put(%q, all) error = %v", key, err)
+		}
+	}
+	if n := copies(store); n != 2*keys {
+		t.Fatalf("%d copies of %d keys before the join, want %d", n, keys, 2*keys)
+	}
+
+	joining := New(WithReplicas(2), WithPruneInterval(100*time.Millisecond))
+	joined, err := Chord.New(ringOptions(Chord.WithVNodes(2), Chord.WithDelegate(joining))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := joining.Attach(joined); err != nil {
+		t.Fatal(err)
+	}
+	if err := joined.Join(ring.Hostnames()[0]); err != nil {
+		t.Fatal(err)
+	}
+	defer joined.Leave()
+	waitForSuccessors(t, 2, ring, joined)
+
+	// Every key ends up on exactly its two replicas, the joined VNodes among
+	// them. Reads may miss until lookups route to the joined VNodes.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		converged := copies(joining) > 0 && copies(store, joining) == 2*keys
+		for i := 0; i < keys && converged; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			value, err := joining.Get(key, All)
+			converged = err == nil && string(value) == key
+		}
+		if converged && copies(store, joining) == 2*keys {
+			return
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if copies(joining) == 0 {
+		t.Error("the joined VNodes store no keys")
+	}
+	if n := copies(store, joining); n != 2*keys {
+		t.Errorf("%d copies of %d keys after the join, want %d", n, keys, 2*keys)
+	}
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if value, err := joining.Get(key, All); err != nil || string(value) != key {
+			t.Errorf("Get(%q, all) = %q, %v, want %q", key, value, err, key)
+		}
+	}
+}
+
+func TestConsistencyRequiredReplicas(t *testing.T) {
+	tests := []struct {
+		c    Consistency
+		n    int
+		want int
+	}{
+		{One, 3, 1},
+		{Quorum, 1, 1},
+		{Quorum, 2, 2},
+		{Quorum, 3, 2},
+		{Quorum, 5, 3},
+		{All, 3, 3},
+	}
+	for _, test := range tests {
+		if got := test.c.required(test.n); got != test.want {
+			t.Errorf("%s.required(%d) = %d, want %d", test.c, test.n, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package kv
+
+// Service serves the keys of one VNode over RPC.
+type Service struct {
+	store    *Store
+	hostname string
+}
+
+type GetArgs struct {
+	Key string
+}
+type GetReply struct {
+	Versions Versions
+}
+
+// PutArgs writes Value having read Context.
+type PutArgs struct {
+	Key     string
+	Value   []byte
+	Context VClock
+}
+
+// PutReply carries the versions after the write, for the other replicas.
+type PutReply struct {
+	Found    bool
+	Versions Versions
+}
+
+// DeleteArgs deletes the values seen in Context.
+type DeleteArgs struct {
+	Key     string
+	Context VClock
+}
+type DeleteReply struct {
+	Found    bool
+	Versions Versions
+}
+
+// TransferArgs carries the encoded versions of keys handed over by another VNode.
+type TransferArgs struct {
+	Items map[string][]byte
+}
+type TransferReply struct{}
+
+// Get returns the versions of a key stored by the VNode.
+func (service *Service) Get(args *GetArgs, reply *GetReply) error {
+	var err error
+	reply.Versions, err = service.store.read(service.hostname, args.Key)
+	return err
+}
+
+// Put writes a version of a key stored by the VNode.
+func (service *Service) Put(args *PutArgs, reply *PutReply) error {
+	var err error
+	reply.Found, reply.Versions, err = service.store.apply(service.hostname, args.Key, args.Value, false, args.Context)
+	return err
+}
+
+// Delete writes a tombstone of a key stored by the VNode.
+func (service *Service) Delete(args *DeleteArgs, reply *DeleteReply) error {
+	var err error
+	reply.Found, reply.Versions, err = service.store.apply(service.hostname, args.Key, nil, true, args.Context)
+	return err
+}
+
+// Transfer merges keys handed over by another VNode or written by another replica.
+func (service *Service) Transfer(args *TransferArgs, reply *TransferReply) error {
+	return service.store.merge(service.hostname, args.Items)
+}
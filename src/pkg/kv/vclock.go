@@ -0,0 +1,306 @@
+package kv
+
+// Versioned values.
+// Every write of a key creates a Version identified by a dot, the VNode
+// applying the write and the count of writes it applied to the key, and
+// carries the context the client read before writing. A write supersedes the
+// versions whose dots its context covers and leaves the others as siblings,
+// so concurrent writes are kept side by side until a client resolves them by
+// writing with a context covering all of them. Deletes write tombstones,
+// which are kept so that merging versions from other VNodes cannot resurrect
+// deleted values.
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// VClock is a vector clock mapping actors to the number of their events seen.
+type VClock map[string]uint64
+
+// Dot identifies a single write: the Counter'th write applied by Actor.
+type Dot struct {
+	Actor   string
+	Counter uint64
+}
+
+// Covers reports whether the clock has seen the write of dot.
+func (c VClock) Covers(dot Dot) bool {
+	return c[dot.Actor] >= dot.Counter
+}
+
+// Descends reports whether the clock has seen all events of other.
+func (c VClock) Descends(other VClock) bool {
+	for actor, counter := range other {
+		if c[actor] < counter {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge returns the clock of the events seen by c or other.
+func (c VClock) Merge(other VClock) VClock {
+	merged := make(VClock, len(c))
+	for actor, counter := range c {
+		merged[actor] = counter
+	}
+	for actor, counter := range other {
+		if counter > merged[actor] {
+			merged[actor] = counter
+		}
+	}
+	return merged
+}
+
+// Version is a value of a key, or a tombstone if Deleted.
+type Version struct {
+	Value   []byte
+	Deleted bool
+	// Dot identifies the write of the version.
+	Dot Dot
+	// Context is the clock the writer had read.
+	Context VClock
+}
+
+// Clock returns the events the version has seen, including its own write.
+func (v Version) Clock() VClock {
+	return v.Context.Merge(VClock{v.Dot.Actor: v.Dot.Counter})
+}
+
+// Versions are the sibling versions of a key.
+type Versions []Version
+
+// Context returns the clock covering all versions, the causal context of a
+// write resolving them.
+func (vs Versions) Context() VClock {
+	context := VClock{}
+	for _, v := range vs {
+		context = context.Merge(v.Clock())
+	}
+	return context
+}
+
+// Values returns the values of the versions which are not tombstones.
+func (vs Versions) Values() [][]byte {
+	values := make([][]byte, 0, len(vs))
+	for _, v := range vs {
+		if !v.Deleted {
+			values = append(values, v.Value)
+		}
+	}
+	return values
+}
+
+// Write returns the versions after actor wrote value, or a tombstone if
+// deleted, having read context. The versions covered by context are dropped.
+func (vs Versions) Write(actor string, value []byte, deleted bool, context VClock) Versions {
+	// The clocks of the versions may have seen writes of actor whose versions
+	// are superseded, the new dot must follow those too.
+	counter := vs.Context().Merge(context)[actor]
+	result := make(Versions, 0, len(vs)+1)
+	for _, v := range vs {
+		if !context.Covers(v.Dot) {
+			result = append(result, v)
+		}
+	}
+
+	return append(result, Version{
+		Value:   value,
+		Deleted: deleted,
+		Dot:     Dot{Actor: actor, Counter: counter + 1},
+		Context: context.Merge(nil),
+	})
+}
+
+// Merge returns the versions of vs and others which are not superseded by a version of the other.
+func (vs Versions) Merge(others Versions) Versions {
+	result := make(Versions, 0, len(vs)+len(others))
+	seen := make(map[Dot]bool)
+	add := func(v Version, superseding Versions) {
+		if seen[v.Dot] {
+			return
+		}
+		for _, w := range superseding {
+			if w.Dot != v.Dot && w.Context.Covers(v.Dot) {
+				return
+			}
+		}
+		seen[v.Dot] = true
+		result = append(result, v)
+	}
+
+	for _, v := range vs {
+		add(v, others)
+	}
+	for _, v := range others {
+		add(v, vs)
+	}
+	return result
+}
+
+// Equal reports whether vs and others hold the same versions.
+func (vs Versions) Equal(others Versions) bool {
+	if len(vs) != len(others) {
+		return false
+	}
+	dots := make(map[Dot]bool, len(vs))
+	for _, v := range vs {
+		dots[v.Dot] = true
+	}
+	for _, v := range others {
+		if !dots[v.Dot] {
+			return false
+		}
+	}
+	return true
+}
+
+// versionsFormat is the first byte of encoded versions.
+const versionsFormat byte = 1
+
+// ErrMalformed is returned for undecodable versions and contexts.
+var ErrMalformed = errors.New("kv: malformed versions")
+
+// appendUvarint appends x to buf as an unsigned varint.
+func appendUvarint(buf []byte, x uint64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	return append(buf, scratch[:binary.PutUvarint(scratch[:], x)]...)
+}
+
+// appendBytes appends the length of b and b to buf.
+func appendBytes(buf []byte, b []byte) []byte {
+	return append(appendUvarint(buf, uint64(len(b))), b...)
+}
+
+// appendClock appends the entries of clock to buf, sorted by actor.
+func appendClock(buf []byte, clock VClock) []byte {
+	actors := make([]string, 0, len(clock))
+	for actor := range clock {
+		actors = append(actors, actor)
+	}
+	sort.Strings(actors)
+
+	buf = appendUvarint(buf, uint64(len(actors)))
+	for _, actor := range actors {
+		buf = appendBytes(buf, []byte(actor))
+		buf = appendUvarint(buf, clock[actor])
+	}
+	return buf
+}
+
+// decoder reads the fields written by the append functions.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = ErrMalformed
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return x
+}
+
+func (d *decoder) bytes() []byte {
+	length := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.buf)) < length {
+		d.err = ErrMalformed
+		return nil
+	}
+	b := d.buf[:length:length]
+	d.buf = d.buf[length:]
+	return b
+}
+
+func (d *decoder) clock() VClock {
+	n := d.uvarint()
+	if d.err != nil || n > uint64(len(d.buf)) {
+		d.err = ErrMalformed
+		return nil
+	}
+	clock := make(VClock, n)
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		actor := string(d.bytes())
+		clock[actor] = d.uvarint()
+	}
+	return clock
+}
+
+// encodeVersions encodes versions for storage.
+func encodeVersions(vs Versions) []byte {
+	buf := []byte{versionsFormat}
+	buf = appendUvarint(buf, uint64(len(vs)))
+	for _, v := range vs {
+		var flags byte
+		if v.Deleted {
+			flags = 1
+		}
+		buf = append(buf, flags)
+		buf = appendBytes(buf, []byte(v.Dot.Actor))
+		buf = appendUvarint(buf, v.Dot.Counter)
+		buf = appendClock(buf, v.Context)
+		buf = appendBytes(buf, v.Value)
+	}
+	return buf
+}
+
+// decodeVersions decodes versions encoded by encodeVersions.
+func decodeVersions(data []byte) (Versions, error) {
+	if len(data) == 0 || data[0] != versionsFormat {
+		return nil, ErrMalformed
+	}
+
+	d := &decoder{buf: data[1:]}
+	n := d.uvarint()
+	if d.err != nil || n > uint64(len(d.buf)) {
+		return nil, ErrMalformed
+	}
+	vs := make(Versions, 0, n)
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		if len(d.buf) == 0 {
+			return nil, ErrMalformed
+		}
+		v := Version{Deleted: d.buf[0]&1 != 0}
+		d.buf = d.buf[1:]
+		v.Dot.Actor = string(d.bytes())
+		v.Dot.Counter = d.uvarint()
+		v.Context = d.clock()
+		v.Value = d.bytes()
+		vs = append(vs, v)
+	}
+	if d.err != nil || len(d.buf) != 0 {
+		return nil, ErrMalformed
+	}
+	return vs, nil
+}
+
+// EncodeContext encodes a causal context for clients, as URL safe base64.
+func EncodeContext(context VClock) string {
+	return base64.RawURLEncoding.EncodeToString(appendClock(nil, context))
+}
+
+// DecodeContext decodes a causal context encoded by EncodeContext.
+func DecodeContext(s string) (VClock, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	d := &decoder{buf: data}
+	context := d.clock()
+	if d.err != nil || len(d.buf) != 0 {
+		return nil, ErrMalformed
+	}
+	return context, nil
+}
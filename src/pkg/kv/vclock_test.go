@@ -0,0 +1,165 @@
+package kv
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+)
+
+// values returns the sorted values of the versions which are not tombstones.
+func values(vs Versions) []string {
+	result := make([]string, 0, len(vs))
+	for _, value := range vs.Values() {
+		result = append(result, string(value))
+	}
+	sort.Strings(result)
+	return result
+}
+
+// checkValues fails the test unless vs hold exactly want.
+func checkValues(t *testing.T, name string, vs Versions, want ...string) {
+	t.Helper()
+	got := values(vs)
+	if len(got) != len(want) {
+		t.Errorf("%s: values %q, want %q", name, got, want)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s: values %q, want %q", name, got, want)
+			return
+		}
+	}
+}
+
+func TestWriteSupersedesCoveredVersions(t *testing.T) {
+	var vs Versions
+	vs = vs.Write("a", []byte("1"), false, nil)
+	vs = vs.Write("a", []byte("2"), false, vs.Context())
+	checkValues(t, "write with context", vs, "2")
+
+	vs = vs.Write("b", []byte("3"), false, nil)
+	checkValues(t, "blind write", vs, "2", "3")
+
+	stale := VClock{"a": 1}
+	vs = vs.Write("a", []byte("4"), false, stale)
+	checkValues(t, "write with stale context", vs, "2", "3", "4")
+
+	vs = vs.Write("b", nil, true, vs.Context())
+	checkValues(t, "delete resolving siblings", vs)
+	if len(vs) != 1 || !vs[0].Deleted {
+		t.Errorf("delete left %d versions, want a single tombstone", len(vs))
+	}
+}
+
+func TestWriteFollowsSupersededDots(t *testing.T) {
+	// a writes, b overwrites it and a merges b's version.
+	onA := Versions(nil).Write("a", []byte("1"), false, nil)
+	onB := onA.Write("b", []byte("2"), false, onA.Context())
+	onA = onA.Merge(onB)
+	checkValues(t, "merged on a", onA, "2")
+
+	// A blind write on a must not reuse the dot of its superseded write.
+	onA = onA.Write("a", []byte("3"), false, nil)
+	for _, v := range onA {
+		if v.Dot == (Dot{Actor: "a", Counter: 1}) {
+			t.Fatalf("blind write reused superseded dot %v", v.Dot)
+		}
+	}
+	checkValues(t, "blind write on a", onA, "2", "3")
+
+	onB = onB.Merge(onA)
+	checkValues(t, "merged on b", onB, "2", "3")
+}
+
+func TestMerge(t *testing.T) {
+	base := Versions(nil).Write("a", []byte("1"), false, nil)
+	left := base.Write("a", []byte("2"), false, base.Context())
+	right := base.Write("b", []byte("3"), false, base.Context())
+
+	tests := []struct {
+		name          string
+		vs, others    Versions
+		want          []string
+		wantVersions  int
+		wantEqualToVs bool
+	}{
+		{"newer replaces older", base, left, []string{"2"}, 1, false},
+		{"older is dropped", left, base, []string{"2"}, 1, true},
+		{"concurrent become siblings", left, right, []string{"2", "3"}, 2, false},
+		{"identical", left, left, []string{"2"}, 1, true},
+		{"empty", nil, right, []string{"3"}, 1, false},
+	}
+	for _, test := range tests {
+		merged := test.vs.Merge(test.others)
+		checkValues(t, test.name, merged, test.want...)
+		if len(merged) != test.wantVersions {
+			t.Errorf("%s: %d versions, want %d", test.name, len(merged), test.wantVersions)
+		}
+		if equal := merged.Equal(test.vs); equal != test.wantEqualToVs {
+			t.Errorf("%s: Equal(vs) = %v, want %v", test.name, equal, test.wantEqualToVs)
+		}
+		if !merged.Equal(test.others.Merge(test.vs)) {
+			t.Errorf("%s: merge is not commutative", test.name)
+		}
+	}
+}
+
+func TestTombstonesPreventResurrection(t *testing.T) {
+	written := Versions(nil).Write("a", []byte("1"), false, nil)
+	deleted := written.Write("b", nil, true, written.Context())
+
+	merged := written.Merge(deleted)
+	checkValues(t, "stale value merged into tombstone", merged)
+	if !merged.Equal(deleted) {
+		t.Errorf("merge kept %d versions, want the tombstone", len(merged))
+	}
+}
+
+func TestEncodeVersions(t *testing.T) {
+	var vs Versions
+	vs = vs.Write("a", []byte("1"), false, nil)
+	vs = vs.Write("b", []byte{}, false, nil)
+	vs = vs.Write("c", nil, true, VClock{"a": 1})
+
+	decoded, err := decodeVersions(encodeVersions(vs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(vs) {
+		t.Fatalf("decoded %v, want %v", decoded, vs)
+	}
+	for i, v := range decoded {
+		if !bytes.Equal(v.Value, vs[i].Value) || v.Deleted != vs[i].Deleted || !v.Context.Descends(vs[i].Context) || !vs[i].Context.Descends(v.Context) {
+			t.Errorf("version %d decoded as %+v, want %+v", i, v, vs[i])
+		}
+	}
+
+	if empty, err := decodeVersions(encodeVersions(nil)); err != nil || len(empty) != 0 {
+		t.Errorf("decoding no versions: %v, %v", empty, err)
+	}
+
+	encoded := encodeVersions(vs)
+	for _, malformed := range [][]byte{nil, {0}, encoded[:len(encoded)-1], append(encoded, 0)} {
+		if _, err := decodeVersions(malformed); err != ErrMalformed {
+			t.Errorf("decodeVersions(%x) error = %v, want ErrMalformed", malformed, err)
+		}
+	}
+}
+
+func TestEncodeContext(t *testing.T) {
+	context := VClock{"127.0.0.1:8000": 3, "127.0.0.1:8001": 1}
+	decoded, err := DecodeContext(EncodeContext(context))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Descends(context) || !context.Descends(decoded) {
+		t.Errorf("decoded %v, want %v", decoded, context)
+	}
+
+	for _, malformed := range []string{"!", EncodeContext(context) + "A"} {
+		if _, err := DecodeContext(malformed); err != ErrMalformed {
+			t.Errorf("DecodeContext(%q) error = %v, want ErrMalformed", malformed, err)
+		}
+	}
+}
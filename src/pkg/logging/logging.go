@@ -0,0 +1,332 @@
+package logging
+
+// Leveled, structured logging.
+// Loggers are created from a Registry which holds the output format and
+// the log level of every component, so levels can be changed at runtime.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+const (
+	// DebugLevel logs everything, including per-call protocol traces.
+	DebugLevel Level = iota
+	// InfoLevel logs state changes of the ring.
+	InfoLevel
+	// WarnLevel logs recoverable failures.
+	WarnLevel
+	// ErrorLevel logs failures only.
+	ErrorLevel
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	default:
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+}
+
+// ParseLevel parses a level name.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("unknown log level %q", name)
+	}
+}
+
+// Format is the encoding of log entries.
+type Format int
+
+const (
+	// LogfmtFormat writes entries as key=value pairs.
+	LogfmtFormat Format = iota
+	// JSONFormat writes entries as JSON objects, one per line.
+	JSONFormat
+)
+
+// ParseFormat parses a format name, either "logfmt" or "json".
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "logfmt", "text":
+		return LogfmtFormat, nil
+	case "json":
+		return JSONFormat, nil
+	default:
+		return LogfmtFormat, fmt.Errorf("unknown log format %q", name)
+	}
+}
+
+// Registry holds the output and per-component levels shared by Loggers.
+type Registry struct {
+	mu           sync.RWMutex
+	out          io.Writer
+	format       Format
+	defaultLevel Level
+	levels       map[string]Level
+	components   map[string]bool
+}
+
+// NewRegistry creates a Registry writing to out.
+// Components without an explicit level log at defaultLevel.
+func NewRegistry(out io.Writer, format Format, defaultLevel Level) *Registry {
+	return &Registry{
+		out:          out,
+		format:       format,
+		defaultLevel: defaultLevel,
+		levels:       make(map[string]Level),
+		components:   make(map[string]bool),
+	}
+}
+
+// Logger returns a Logger for component.
+func (r *Registry) Logger(component string) *Logger {
+	r.mu.Lock()
+	r.components[component] = true
+	r.mu.Unlock()
+
+	return &Logger{registry: r, component: component}
+}
+
+// SetDefaultLevel sets the level of components without an explicit level.
+func (r *Registry) SetDefaultLevel(level Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.defaultLevel = level
+}
+
+// SetLevel sets the level of a single component.
+func (r *Registry) SetLevel(component string, level Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.levels[component] = level
+	r.components[component] = true
+}
+
+// SetLevels parses a comma separated list of component=level pairs
+// and applies them, e.g. "vnode=debug,rpc=warn".
+func (r *Registry) SetLevels(spec string) error {
+	levels := make(map[string]Level)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return fmt.Errorf("invalid component level %q, expected component=level", pair)
+		}
+
+		level, err := ParseLevel(parts[1])
+		if err != nil {
+			return err
+		}
+		levels[strings.TrimSpace(parts[0])] = level
+	}
+
+	for component, level := range levels {
+		r.SetLevel(component, level)
+	}
+
+	return nil
+}
+
+// Level returns the effective level of component.
+func (r *Registry) Level(component string) Level {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if level, ok := r.levels[component]; ok {
+		return level
+	}
+	return r.defaultLevel
+}
+
+// Levels returns the effective level of every known component.
+// The default level is listed under the "default" key.
+func (r *Registry) Levels() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	levels := map[string]string{"default": r.defaultLevel.String()}
+	for component := range r.components {
+		level, ok := r.levels[component]
+		if !ok {
+			level = r.defaultLevel
+		}
+		levels[component] = level.String()
+	}
+
+	return levels
+}
+
+// write encodes a single entry and writes it to the output.
+func (r *Registry) write(level Level, component string, msg string, fields []interface{}) {
+	keyvals := make([]interface{}, 0, len(fields)+8)
+	keyvals = append(keyvals,
+		"time", time.Now().UTC().Format(time.RFC3339Nano),
+		"level", level.String(),
+		"component", component,
+		"msg", msg,
+	)
+	keyvals = append(keyvals, fields...)
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "(MISSING)")
+	}
+
+	var buf bytes.Buffer
+	switch r.format {
+	case JSONFormat:
+		encodeJSON(&buf, keyvals)
+	default:
+		encodeLogfmt(&buf, keyvals)
+	}
+	buf.WriteByte('\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.out.Write(buf.Bytes())
+}
+
+// encodeLogfmt writes keyvals as space separated key=value pairs.
+func encodeLogfmt(buf *bytes.Buffer, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(fmt.Sprint(keyvals[i]))
+		buf.WriteByte('=')
+
+		value := formatValue(keyvals[i+1])
+		if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+			value = strconv.Quote(value)
+		}
+		buf.WriteString(value)
+	}
+}
+
+// encodeJSON writes keyvals as a JSON object preserving their order.
+func encodeJSON(buf *bytes.Buffer, keyvals []interface{}) {
+	buf.WriteByte('{')
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(keyvals[i]))
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		var value []byte
+		var err error
+		switch v := keyvals[i+1].(type) {
+		case error:
+			value, err = json.Marshal(v.Error())
+		case fmt.Stringer:
+			value, err = json.Marshal(v.String())
+		default:
+			value, err = json.Marshal(v)
+		}
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(keyvals[i+1]))
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case error:
+		return v.Error()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Logger writes leveled entries for a component with a set of context fields.
+type Logger struct {
+	registry  *Registry
+	component string
+	fields    []interface{}
+}
+
+// With returns a Logger which adds keyvals to every entry.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+
+	return &Logger{registry: l.registry, component: l.component, fields: fields}
+}
+
+// Component returns the component name of the Logger.
+func (l *Logger) Component() string {
+	return l.component
+}
+
+// Enabled reports whether entries at level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.registry.Level(l.component)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	l.registry.write(level, l.component, msg, fields)
+}
+
+// Debug logs msg with keyvals at DebugLevel.
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(DebugLevel, msg, keyvals)
+}
+
+// Info logs msg with keyvals at InfoLevel.
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(InfoLevel, msg, keyvals)
+}
+
+// Warn logs msg with keyvals at WarnLevel.
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(WarnLevel, msg, keyvals)
+}
+
+// Error logs msg with keyvals at ErrorLevel.
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(ErrorLevel, msg, keyvals)
+}
@@ -12,7 +12,7 @@ import (
 
 type ChordProtocolRPC interface {
 	// FindSuccessors finds N successors of the VNode.
-	// FindSuccessors(int) ([]*VNode.VNodeProtocol, error)
+	FindSuccessors(int) ([]VNode.VNodeProtocol, error)
 
 	// FindSuccessor finds the successor for a Key.
 	FindSuccessor(uint64) (VNode.VNodeProtocol, error)
@@ -23,8 +23,29 @@ type ChordProtocolRPC interface {
 	// Ping sends a request to a VNode
 	Ping() error
 
+	// Identify pings the VNode and returns the ID and hostname it reports.
+	Identify() (uint64, string, error)
+
 	// GetPredecessor returns the predecessor VNode.
 	GetPredecessor() (VNode.VNodeProtocol, error)
+
+	// NotifyLeave informs the VNode of a leaving VNode and its neighbours.
+	NotifyLeave(VNode.VNodeProtocol, VNode.VNodeProtocol, VNode.VNodeProtocol) error
+
+	// Route forwards an application message towards the owner of its key.
+	Route(*VNode.Message) error
+
+	// Deliver hands an application message to the VNode owning its key.
+	Deliver(*VNode.Message) error
+
+	// Broadcast hands an application message to the VNode and the VNodes up to its limit.
+	Broadcast(*VNode.BroadcastMessage) (*VNode.BroadcastReply, error)
+
+	// Info returns the routing state of the VNode.
+	Info() (*VNode.Info, error)
+
+	// Call invokes a method of an application service registered on the VNode.
+	Call(serviceMethod string, args interface{}, reply interface{}) error
 }
 
 type FindSuccRpcArgs struct {
@@ -32,17 +53,67 @@ type FindSuccRpcArgs struct {
 }
 type FindSuccRpcReply struct {
 	Hostname string
+	Identity VNode.Identity
+}
+
+type FindSuccsRpcArgs struct {
+	N int
+}
+type FindSuccsRpcReply struct {
+	Hostnames  []string
+	Identities []VNode.Identity
 }
 
 type NotifyRpcArgs struct {
 	Hostname string
+	Identity VNode.Identity
 }
 type NotifyRpcReply struct{}
 
 type PingRpcArgs struct{}
-type PingRpcReply struct{}
+type PingRpcReply struct {
+	// ID and Hostname identify the VNode answering.
+	ID       uint64
+	Hostname string
+}
 
 type GetPredecessorRpcArgs struct{}
 type GetPredecessorRpcReply struct {
 	Hostname string
+	Identity VNode.Identity
+}
+
+// NotifyLeaveRpcArgs carries the leaving VNode and its neighbours,
+// an empty hostname stands for an unknown neighbour.
+type NotifyLeaveRpcArgs struct {
+	Hostname            string
+	PredecessorHostname string
+	SuccessorHostname   string
+
+	Identity            VNode.Identity
+	PredecessorIdentity VNode.Identity
+	SuccessorIdentity   VNode.Identity
+}
+type NotifyLeaveRpcReply struct{}
+
+type RouteRpcArgs struct {
+	Message VNode.Message
+}
+type RouteRpcReply struct{}
+
+type DeliverRpcArgs struct {
+	Message VNode.Message
+}
+type DeliverRpcReply struct{}
+
+type BroadcastRpcArgs struct {
+	Message VNode.BroadcastMessage
+}
+type BroadcastRpcReply struct {
+	Reply VNode.BroadcastReply
+}
+
+type InfoRpcArgs struct{}
+type InfoRpcReply struct {
+	Info VNode.Info
 }
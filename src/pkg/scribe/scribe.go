@@ -0,0 +1,474 @@
+package scribe
+
+// Scribe style topic based publish/subscribe on a Chord ring.
+// A topic hashes to a rendezvous VNode, the owner of the topic's ID.
+// Subscribers route a join towards the rendezvous VNode, every VNode on the
+// way records the previous hop as its child, building a multicast tree
+// rooted at the rendezvous VNode. Publishes are routed to the rendezvous VNode
+// and disseminated down the tree.
+// Tree links are soft state, VNodes on a tree refresh their join periodically
+// and children which have not refreshed within three intervals are dropped.
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+	Hash "github.com/arush15june/chord-golang/src/pkg/hash"
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+const (
+	// ApplicationName is the name Scribe registers its routing application under.
+	ApplicationName = "scribe"
+
+	// DefaultRefreshInterval is the default period between refreshing tree links.
+	DefaultRefreshInterval = 10 * time.Second
+
+	// subscriptionBuffer is the number of events buffered for a subscriber,
+	// events for a full subscriber are dropped.
+	subscriptionBuffer = 64
+
+	// seenMessages is the number of published message IDs remembered per VNode
+	// to drop duplicates arriving over stale tree links.
+	seenMessages = 1024
+)
+
+// ErrClosed is returned when subscribing to or publishing on a closed Scribe.
+var ErrClosed = errors.New("scribe: closed")
+
+type messageType int
+
+const (
+	// joinMessage is routed towards the rendezvous VNode by tree members.
+	joinMessage messageType = iota
+	// publishMessage is routed towards the rendezvous VNode by publishers.
+	publishMessage
+	// multicastMessage is sent down the tree from parents to children.
+	multicastMessage
+)
+
+// message is the payload of the routed messages.
+type message struct {
+	Type  messageType
+	Topic string
+	// Sender is the hostname of the VNode which forwarded a join,
+	// it becomes a child of the VNode receiving the join.
+	Sender string
+	// ID identifies a published message.
+	ID   uint64
+	Data []byte
+}
+
+func (m *message) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decode(payload []byte) (*message, error) {
+	m := &message{}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Event is a message published on a topic.
+type Event struct {
+	Topic string
+	Data  []byte
+}
+
+// Subscription receives the events published on a topic.
+type Subscription struct {
+	// C receives the events, it is closed when the subscription is closed.
+	C <-chan Event
+
+	c      chan Event
+	topic  string
+	scribe *Scribe
+}
+
+// Topic returns the topic subscribed to.
+func (sub *Subscription) Topic() string {
+	return sub.topic
+}
+
+// Close unsubscribes from the topic.
+func (sub *Subscription) Close() {
+	sub.scribe.unsubscribe(sub)
+}
+
+// tree is the part of a topic's multicast tree held by a VNode.
+type tree struct {
+	// children maps the hostnames of the children to their last join.
+	children map[string]time.Time
+}
+
+// seenKey identifies a published message delivered to a VNode.
+type seenKey struct {
+	hostname string
+	id       uint64
+}
+
+// Scribe publishes and subscribes to topics on a Ring.
+type Scribe struct {
+	ring    *Chord.Ring
+	log     *Logging.Logger
+	refresh time.Duration
+
+	mu sync.Mutex
+	// trees maps VNode hostnames to the trees they are part of by topic.
+	trees         map[string]map[string]*tree
+	subscriptions map[string]map[*Subscription]struct{}
+	seen          map[seenKey]struct{}
+	seenOrder     []seenKey
+	closed        bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New registers a Scribe on ring, tree links are refreshed every refresh interval.
+// It must be created on every Ring taking part in publish/subscribe.
+func New(ring *Chord.Ring, refresh time.Duration) *Scribe {
+	s := &Scribe{
+		ring:          ring,
+		log:           ring.Logs().Logger("scribe"),
+		refresh:       refresh,
+		trees:         make(map[string]map[string]*tree),
+		subscriptions: make(map[string]map[*Subscription]struct{}),
+		seen:          make(map[seenKey]struct{}),
+		stop:          make(chan struct{}),
+	}
+
+	ring.Register(ApplicationName, s)
+	go s.refreshRoutine()
+
+	return s
+}
+
+// member returns the local VNode the subscriptions of the Ring are attached to.
+func (s *Scribe) member() *Chord.LocalVNode {
+	return s.ring.VNodes()[0]
+}
+
+// treeOf returns the tree of topic at the VNode, creating it if create is set.
+// It must be called with s.mu held.
+func (s *Scribe) treeOf(hostname string, topic string, create bool) *tree {
+	topics, ok := s.trees[hostname]
+	if !ok {
+		if !create {
+			return nil
+		}
+		topics = make(map[string]*tree)
+		s.trees[hostname] = topics
+	}
+
+	t, ok := topics[topic]
+	if !ok && create {
+		t = &tree{children: make(map[string]time.Time)}
+		topics[topic] = t
+	}
+	return t
+}
+
+// Subscribe subscribes to topic. The subscription joins the topic's tree
+// in the background if the ring has not been joined yet.
+func (s *Scribe) Subscribe(topic string) (*Subscription, error) {
+	c := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{C: c, c: c, topic: topic, scribe: s}
+	member := s.member()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, ErrClosed
+	}
+	subs, ok := s.subscriptions[topic]
+	if !ok {
+		subs = make(map[*Subscription]struct{})
+		s.subscriptions[topic] = subs
+	}
+	subs[sub] = struct{}{}
+	s.treeOf(member.Hostname(), topic, true)
+	s.mu.Unlock()
+
+	s.log.Info("Subscribed", "topic", topic)
+	if err := s.join(member, topic); err != nil {
+		s.log.Warn("Failed to join topic tree, retrying on refresh", "topic", topic, "err", err)
+	}
+
+	return sub, nil
+}
+
+func (s *Scribe) unsubscribe(sub *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := s.subscriptions[sub.topic]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(s.subscriptions, sub.topic)
+	}
+	close(sub.c)
+
+	s.log.Info("Unsubscribed", "topic", sub.topic)
+}
+
+// Publish publishes data on topic. It returns once the rendezvous VNode
+// of the topic has received the message, the tree is disseminated to in the background.
+func (s *Scribe) Publish(topic string, data []byte) error {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	m := &message{Type: publishMessage, Topic: topic, ID: rand.Uint64(), Data: data}
+	payload, err := m.encode()
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug("Publishing", "topic", topic, "id", m.ID, "size", len(data))
+	return s.ring.Route(ApplicationName, topic, payload)
+}
+
+// join routes a join for topic from vnode towards the rendezvous VNode.
+func (s *Scribe) join(vnode *Chord.LocalVNode, topic string) error {
+	if !s.ring.Ready() {
+		return Chord.ErrNotReady
+	}
+
+	m := &message{Type: joinMessage, Topic: topic, Sender: vnode.Hostname()}
+	payload, err := m.encode()
+	if err != nil {
+		return err
+	}
+
+	return vnode.Route(&Chord.Message{
+		Application: ApplicationName,
+		Key:         Hash.Sum([]byte(topic)),
+		Payload:     payload,
+		Origin:      vnode.Hostname(),
+	})
+}
+
+// Forward is called on every hop of a routed message. A join adds the previous
+// hop as a child of the local VNode and stops at VNodes already on the tree.
+func (s *Scribe) Forward(local *Chord.LocalVNode, msg *Chord.Message, next VNode.VNodeProtocol) bool {
+	m, err := decode(msg.Payload)
+	if err != nil {
+		s.log.Warn("Dropping undecodable message", "origin", msg.Origin, "err", err)
+		return false
+	}
+	if m.Type != joinMessage || m.Sender == local.Hostname() {
+		return true
+	}
+
+	s.mu.Lock()
+	onTree := s.treeOf(local.Hostname(), m.Topic, false) != nil
+	s.treeOf(local.Hostname(), m.Topic, true).children[m.Sender] = time.Now()
+	s.mu.Unlock()
+
+	s.log.Debug("Added child", "topic", m.Topic, "vnode", local.Hostname(), "child", m.Sender)
+	if onTree {
+		return false
+	}
+
+	m.Sender = local.Hostname()
+	payload, err := m.encode()
+	if err != nil {
+		s.log.Warn("Failed to forward join", "topic", m.Topic, "err", err)
+		return false
+	}
+	msg.Payload = payload
+
+	return true
+}
+
+// Deliver is called on the rendezvous VNode of a routed message and on children receiving a multicast.
+func (s *Scribe) Deliver(local *Chord.LocalVNode, msg *Chord.Message) {
+	m, err := decode(msg.Payload)
+	if err != nil {
+		s.log.Warn("Dropping undecodable message", "origin", msg.Origin, "err", err)
+		return
+	}
+
+	switch m.Type {
+	case joinMessage:
+		if m.Sender == local.Hostname() {
+			return
+		}
+		s.mu.Lock()
+		s.treeOf(local.Hostname(), m.Topic, true).children[m.Sender] = time.Now()
+		s.mu.Unlock()
+		s.log.Debug("Added child at rendezvous", "topic", m.Topic, "vnode", local.Hostname(), "child", m.Sender)
+	case publishMessage, multicastMessage:
+		s.disseminate(local, msg.Key, m)
+	}
+}
+
+// disseminate delivers m to the subscribers attached to local and sends it to its children.
+func (s *Scribe) disseminate(local *Chord.LocalVNode, key uint64, m *message) {
+	s.mu.Lock()
+	if s.markSeen(seenKey{local.Hostname(), m.ID}) {
+		s.mu.Unlock()
+		return
+	}
+
+	t := s.treeOf(local.Hostname(), m.Topic, false)
+	if t == nil {
+		s.mu.Unlock()
+		return
+	}
+	children := make([]string, 0, len(t.children))
+	for child := range t.children {
+		children = append(children, child)
+	}
+
+	if local.Hostname() == s.member().Hostname() {
+		for sub := range s.subscriptions[m.Topic] {
+			select {
+			case sub.c <- Event{Topic: m.Topic, Data: m.Data}:
+			default:
+				s.log.Warn("Subscriber is full, dropping event", "topic", m.Topic, "id", m.ID)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if len(children) == 0 {
+		return
+	}
+
+	m.Type = multicastMessage
+	payload, err := m.encode()
+	if err != nil {
+		s.log.Warn("Failed to multicast", "topic", m.Topic, "err", err)
+		return
+	}
+
+	for _, child := range children {
+		go s.send(local, child, &Chord.Message{
+			Application: ApplicationName,
+			Key:         key,
+			Payload:     payload,
+			Origin:      local.Hostname(),
+		}, m.Topic)
+	}
+}
+
+// send delivers msg to child, children which cannot be reached are dropped from the tree.
+func (s *Scribe) send(local *Chord.LocalVNode, child string, msg *Chord.Message, topic string) {
+	err := s.ring.Transport().Remote(child).Deliver(msg)
+	if err == nil {
+		return
+	}
+
+	s.log.Warn("Failed to multicast to child, dropping it", "topic", topic, "vnode", local.Hostname(), "child", child, "err", err)
+
+	s.mu.Lock()
+	if t := s.treeOf(local.Hostname(), topic, false); t != nil {
+		delete(t.children, child)
+	}
+	s.mu.Unlock()
+}
+
+// markSeen records key and reports whether it had been seen before.
+// It must be called with s.mu held.
+func (s *Scribe) markSeen(key seenKey) bool {
+	if _, ok := s.seen[key]; ok {
+		return true
+	}
+
+	s.seen[key] = struct{}{}
+	s.seenOrder = append(s.seenOrder, key)
+	if len(s.seenOrder) > seenMessages {
+		delete(s.seen, s.seenOrder[0])
+		s.seenOrder = s.seenOrder[1:]
+	}
+	return false
+}
+
+// refreshRoutine periodically expires stale children and refreshes the joins of the local VNodes.
+func (s *Scribe) refreshRoutine() {
+	ticker := time.NewTicker(s.refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.refreshTrees()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// refreshTrees drops children which have not joined within three refresh intervals,
+// prunes trees without children or subscribers and re-joins the remaining ones.
+func (s *Scribe) refreshTrees() {
+	member := s.member().Hostname()
+	expiry := time.Now().Add(-3 * s.refresh)
+	joins := make(map[string][]string)
+
+	s.mu.Lock()
+	for hostname, topics := range s.trees {
+		for topic, t := range topics {
+			for child, joined := range t.children {
+				if joined.Before(expiry) {
+					delete(t.children, child)
+					s.log.Debug("Expired child", "topic", topic, "vnode", hostname, "child", child)
+				}
+			}
+
+			subscribed := hostname == member && len(s.subscriptions[topic]) > 0
+			if len(t.children) == 0 && !subscribed {
+				delete(topics, topic)
+				continue
+			}
+			joins[hostname] = append(joins[hostname], topic)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, vnode := range s.ring.VNodes() {
+		for _, topic := range joins[vnode.Hostname()] {
+			if err := s.join(vnode, topic); err != nil {
+				s.log.Warn("Failed to refresh topic tree", "topic", topic, "vnode", vnode.Hostname(), "err", err)
+			}
+		}
+	}
+}
+
+// Close stops refreshing the trees and closes all subscriptions.
+func (s *Scribe) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for topic, subs := range s.subscriptions {
+		for sub := range subs {
+			close(sub.c)
+		}
+		delete(s.subscriptions, topic)
+	}
+}
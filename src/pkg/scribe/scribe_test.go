@@ -0,0 +1,174 @@
+package scribe
+
+import (
+	"testing"
+	"time"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+	Hash "github.com/arush15june/chord-golang/src/pkg/hash"
+)
+
+// newRing returns a ring of one VNode converging quickly, created or joined through seed.
+func newRing(t *testing.T, seed string) *Chord.Ring {
+	t.Helper()
+	ring, err := Chord.New(
+		Chord.WithHostname("127.0.0.1:0"),
+		Chord.WithStabilizeInterval(20*time.Millisecond, 50*time.Millisecond),
+		Chord.WithFixFingerInterval(10*time.Millisecond),
+		Chord.WithMaxFixFingerInterval(50*time.Millisecond),
+		Chord.WithCallTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seed == "" {
+		err = ring.Create()
+	} else {
+		err = ring.Join(seed)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+// receive returns the next event of sub, failing the test if none arrives in time.
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no event on %q", sub.Topic())
+		return Event{}
+	}
+}
+
+func TestPublishReachesSubscribersOnOtherRings(t *testing.T) {
+	first := newRing(t, "")
+	defer first.Leave()
+	second := newRing(t, first.Hostnames()[0])
+	defer second.Leave()
+
+	publisher := New(first, time.Hour)
+	defer publisher.Close()
+	subscriber := New(second, time.Hour)
+	defer subscriber.Close()
+
+	// Subscribe until the join reached the rendezvous VNode through the ring.
+	sub, err := subscriber.Subscribe("news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if err := publisher.Publish("news", []byte("hello")); err == nil {
+			select {
+			case event := <-sub.C:
+				if event.Topic != "news" || string(event.Data) != "hello" {
+					t.Errorf("received %q on %q, want hello on news", event.Data, event.Topic)
+				}
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("published events never reached the subscriber")
+		}
+		subscriber.refreshTrees()
+	}
+}
+
+func TestDuplicatesAreDeliveredOnce(t *testing.T) {
+	ring := newRing(t, "")
+	defer ring.Leave()
+	s := New(ring, time.Hour)
+	defer s.Close()
+
+	sub, err := s.Subscribe("news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	local := ring.VNodes()[0]
+	deliver := func(id uint64, data string) {
+		m := &message{Type: multicastMessage, Topic: "news", ID: id, Data: []byte(data)}
+		payload, err := m.encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Deliver(local, &Chord.Message{Application: ApplicationName, Key: Hash.Sum([]byte("news")), Payload: payload})
+	}
+
+	deliver(1, "first")
+	deliver(1, "first again")
+	deliver(2, "second")
+	if event := receive(t, sub); string(event.Data) != "first" {
+		t.Errorf("received %q, want first", event.Data)
+	}
+	if event := receive(t, sub); string(event.Data) != "second" {
+		t.Errorf("received %q, want second after dropping the duplicate", event.Data)
+	}
+	select {
+	case event := <-sub.C:
+		t.Errorf("received %q, want no more events", event.Data)
+	default:
+	}
+}
+
+func TestSeenMessagesAreBounded(t *testing.T) {
+	s := &Scribe{seen: make(map[seenKey]struct{})}
+	first := seenKey{"127.0.0.1:8000", 0}
+	if s.markSeen(first) || !s.markSeen(first) {
+		t.Fatal("markSeen() did not remember a message")
+	}
+
+	for id := uint64(1); id <= seenMessages; id++ {
+		s.markSeen(seenKey{"127.0.0.1:8000", id})
+	}
+	if len(s.seen) != seenMessages || len(s.seenOrder) != seenMessages {
+		t.Errorf("remembering %d/%d messages, want %d", len(s.seen), len(s.seenOrder), seenMessages)
+	}
+	if s.markSeen(first) {
+		t.Error("the oldest message was not forgotten")
+	}
+}
+
+func TestRefreshExpiresChildren(t *testing.T) {
+	ring := newRing(t, "")
+	defer ring.Leave()
+	s := New(ring, time.Hour)
+	defer s.Close()
+
+	sub, err := s.Subscribe("subscribed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	member := ring.VNodes()[0].Hostname()
+	stale := time.Now().Add(-4 * time.Hour)
+	s.mu.Lock()
+	s.treeOf(member, "relayed", true).children = map[string]time.Time{"127.0.0.1:1": stale, "127.0.0.1:2": time.Now()}
+	s.treeOf(member, "abandoned", true).children = map[string]time.Time{"127.0.0.1:3": stale}
+	s.treeOf(member, "subscribed", true).children["127.0.0.1:4"] = stale
+	s.mu.Unlock()
+
+	s.refreshTrees()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if relayed := s.treeOf(member, "relayed", false); relayed == nil || len(relayed.children) != 1 {
+		t.Errorf("relayed tree %v, want the fresh child only", relayed)
+	} else if _, ok := relayed.children["127.0.0.1:2"]; !ok {
+		t.Errorf("relayed tree kept %v, want the fresh child", relayed.children)
+	}
+	if s.treeOf(member, "abandoned", false) != nil {
+		t.Error("tree with only expired children and no subscribers was kept")
+	}
+	if subscribed := s.treeOf(member, "subscribed", false); subscribed == nil || len(subscribed.children) != 0 {
+		t.Errorf("subscribed tree %v, want it kept without children", subscribed)
+	}
+}
@@ -0,0 +1,407 @@
+package storage
+
+// Disk engine.
+// The keys are held in memory like by the Memory engine, and every change is
+// appended to a write-ahead log and synced before it is applied. Compaction
+// writes all keys to a new snapshot, atomically replaces the old one and
+// truncates the log. Opening loads the snapshot and replays the log, a torn
+// record at the end of the log, left by a crash while appending, is dropped.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+)
+
+const (
+	snapshotFile = "snapshot"
+	walFile      = "wal"
+)
+
+// minCompactSize is the log size above which writes compact the log once it
+// outgrows the snapshot, regardless of the compaction interval.
+const minCompactSize = 4 << 20
+
+// Log record operations.
+const (
+	opPut    byte = 1
+	opDelete byte = 2
+)
+
+// recordHeaderSize is the size of the checksum and the length preceding every record.
+const recordHeaderSize = 8
+
+// maxRecordSize bounds the payload of a record, longer ones are corrupt.
+const maxRecordSize = 1 << 30
+
+// ErrCorrupt is returned when a snapshot fails its checksums.
+var ErrCorrupt = errors.New("storage: corrupt snapshot")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// appendRecord appends the record of op on key and value to buf.
+// A record is the CRC-32C and the length of its payload, followed by the
+// payload: the operation, the length of the key, the key and the value.
+func appendRecord(buf *bytes.Buffer, op byte, key string, value []byte) {
+	payload := make([]byte, 1+binary.MaxVarintLen64, 1+binary.MaxVarintLen64+len(key)+len(value))
+	payload[0] = op
+	n := binary.PutUvarint(payload[1:], uint64(len(key)))
+	payload = append(payload[:1+n], key...)
+	payload = append(payload, value...)
+
+	var header [recordHeaderSize]byte
+	binary.LittleEndian.PutUint32(header[0:4], crc32.Checksum(payload, crcTable))
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(payload)))
+	buf.Write(header[:])
+	buf.Write(payload)
+}
+
+// readRecords applies the records of r to ix and returns the size of the
+// records read. It stops at the end of r or at the first torn or corrupt record,
+// and reports whether the records ended cleanly.
+func readRecords(r io.Reader, ix *index) (int64, bool, error) {
+	reader := bufio.NewReader(r)
+	var size int64
+	var header [recordHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(reader, header[:]); err == io.EOF {
+			return size, true, nil
+		} else if err == io.ErrUnexpectedEOF {
+			return size, false, nil
+		} else if err != nil {
+			return size, false, err
+		}
+
+		length := binary.LittleEndian.Uint32(header[4:8])
+		if length > maxRecordSize {
+			return size, false, nil
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return size, false, nil
+		} else if err != nil {
+			return size, false, err
+		}
+		if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[0:4]) || len(payload) < 1 {
+			return size, false, nil
+		}
+
+		keyLen, n := binary.Uvarint(payload[1:])
+		if n <= 0 || uint64(len(payload)-1-n) < keyLen {
+			return size, false, nil
+		}
+		key := string(payload[1+n : 1+n+int(keyLen)])
+		switch payload[0] {
+		case opPut:
+			ix.put(NewItem(key, payload[1+n+int(keyLen):]))
+		case opDelete:
+			ix.remove(key)
+		default:
+			return size, false, nil
+		}
+		size += int64(recordHeaderSize + len(payload))
+	}
+}
+
+// Disk is an Engine persisting the keys in a directory.
+type Disk struct {
+	mem *Memory
+	dir string
+
+	// mu serializes writes to the log and compactions.
+	mu           sync.Mutex
+	wal          *os.File
+	walSize      int64
+	snapshotSize int64
+	// retrySize is the log size at which writes retry a failed compaction.
+	retrySize int64
+	closed    bool
+
+	log *Logging.Logger
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// OpenDisk opens the Disk engine in dir, creating dir if it does not exist.
+// The log is compacted every compactInterval if it changed, never if 0.
+// Failed compactions are logged to log, discarded if nil.
+func OpenDisk(dir string, compactInterval time.Duration, log *Logging.Logger) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if log == nil {
+		log = Logging.NewRegistry(ioutil.Discard, Logging.LogfmtFormat, Logging.ErrorLevel).Logger("storage")
+	}
+
+	d := &Disk{
+		mem:  NewMemory(),
+		dir:  dir,
+		log:  log,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	if snapshot, err := os.Open(filepath.Join(dir, snapshotFile)); err == nil {
+		size, clean, err := readRecords(snapshot, d.mem.ix)
+		snapshot.Close()
+		if err != nil {
+			return nil, err
+		}
+		if !clean {
+			return nil, fmt.Errorf("%v: %s", ErrCorrupt, filepath.Join(dir, snapshotFile))
+		}
+		d.snapshotSize = size
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	size, clean, err := readRecords(wal, d.mem.ix)
+	if err != nil {
+		wal.Close()
+		return nil, err
+	}
+	if !clean {
+		// Drop the torn tail so that new records follow the last complete one.
+		if err := wal.Truncate(size); err != nil {
+			wal.Close()
+			return nil, err
+		}
+	}
+	if _, err := wal.Seek(size, io.SeekStart); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	d.wal = wal
+	d.walSize = size
+
+	if compactInterval > 0 {
+		go d.compactRoutine(compactInterval)
+	} else {
+		close(d.done)
+	}
+
+	return d, nil
+}
+
+// DiskOpener returns the Opener of Disk engines in the subdirectories of dir
+// named by the hexadecimal ID of their VNode.
+func DiskOpener(dir string, compactInterval time.Duration, log *Logging.Logger) Opener {
+	return func(id uint64) (Engine, error) {
+		return OpenDisk(filepath.Join(dir, fmt.Sprintf("%016x", id)), compactInterval, log)
+	}
+}
+
+// appendLog appends the records in buf to the log and syncs it.
+func (d *Disk) appendLog(buf *bytes.Buffer) error {
+	if d.closed {
+		return errors.New("storage: engine is closed")
+	}
+
+	if _, err := d.wal.Write(buf.Bytes()); err != nil {
+		// Cut a partial record, later records must not follow a torn one.
+		d.wal.Truncate(d.walSize)
+		d.wal.Seek(d.walSize, io.SeekStart)
+		return err
+	}
+	d.walSize += int64(buf.Len())
+	return d.wal.Sync()
+}
+
+func (d *Disk) Get(key string) ([]byte, bool, error) {
+	return d.mem.Get(key)
+}
+
+func (d *Disk) Range(from uint64, to uint64, fn func(item Item) bool) error {
+	return d.mem.Range(from, to, fn)
+}
+
+func (d *Disk) Len() int {
+	return d.mem.Len()
+}
+
+func (d *Disk) Put(items ...Item) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	buf := &bytes.Buffer{}
+	for _, item := range items {
+		appendRecord(buf, opPut, item.Key, item.Value)
+	}
+	if err := d.appendLog(buf); err != nil {
+		return err
+	}
+
+	d.mem.Put(items...)
+	d.maybeCompact()
+	return nil
+}
+
+func (d *Disk) Delete(keys ...string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	buf := &bytes.Buffer{}
+	stored := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok, _ := d.mem.Get(key); ok {
+			appendRecord(buf, opDelete, key, nil)
+			stored = append(stored, key)
+		}
+	}
+	if len(stored) == 0 {
+		return 0, nil
+	}
+	if err := d.appendLog(buf); err != nil {
+		return 0, err
+	}
+
+	removed, _ := d.mem.Delete(stored...)
+	d.maybeCompact()
+	return removed, nil
+}
+
+// maybeCompact compacts the log once it is large and has outgrown the snapshot.
+// The write is durable in the log already, so failures are only logged, and
+// retried once the log grew by another minCompactSize rather than on every write.
+func (d *Disk) maybeCompact() {
+	if d.walSize < minCompactSize || d.walSize < d.snapshotSize || d.walSize < d.retrySize {
+		return
+	}
+	if err := d.compact(); err != nil {
+		d.retrySize = d.walSize + minCompactSize
+		d.log.Error("Failed to compact the log", "dir", d.dir, "size", d.walSize, "err", err)
+	}
+}
+
+// Compact writes a snapshot of all keys and truncates the log.
+func (d *Disk) Compact() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed || d.walSize == 0 {
+		return nil
+	}
+	return d.compact()
+}
+
+func (d *Disk) compact() error {
+	tmpPath := filepath.Join(d.dir, snapshotFile+".tmp")
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(tmp)
+	buf := &bytes.Buffer{}
+	var size int64
+	var writeErr error
+	d.mem.Range(0, 0, func(item Item) bool {
+		buf.Reset()
+		appendRecord(buf, opPut, item.Key, item.Value)
+		size += int64(buf.Len())
+		_, writeErr = writer.Write(buf.Bytes())
+		return writeErr == nil
+	})
+	if writeErr == nil {
+		writeErr = writer.Flush()
+	}
+	if writeErr == nil {
+		writeErr = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		os.Remove(tmpPath)
+		return writeErr
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(d.dir, snapshotFile)); err != nil {
+		return err
+	}
+	if err := syncDir(d.dir); err != nil {
+		return err
+	}
+	d.snapshotSize = size
+
+	// Replaying the log over the new snapshot reproduces it, so a crash before
+	// the log is truncated loses nothing.
+	if err := d.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := d.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	d.walSize = 0
+	d.retrySize = 0
+	return d.wal.Sync()
+}
+
+// syncDir syncs the directory entries of dir, making renames durable.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// compactRoutine compacts the log periodically until the engine is closed.
+func (d *Disk) compactRoutine(interval time.Duration) {
+	defer close(d.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := d.Compact(); err != nil {
+				d.log.Error("Failed to compact the log", "dir", d.dir, "err", err)
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Close compacts the log and closes it.
+func (d *Disk) Close() error {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return nil
+	}
+	var err error
+	if d.walSize > 0 {
+		err = d.compact()
+	}
+	d.closed = true
+	if closeErr := d.wal.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	Logging "github.com/arush15june/chord-golang/src/pkg/logging"
+)
+
+// tempDir returns a new temporary directory and a function removing it.
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "chord-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// crash closes the log of d without compacting it, like a killed process.
+func crash(d *Disk) {
+	d.wal.Close()
+}
+
+// checkValue fails the test unless e stores want for key, or does not store key if want is nil.
+func checkValue(t *testing.T, e Engine, key string, want []byte) {
+	t.Helper()
+	value, ok, err := e.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	if want == nil && ok {
+		t.Errorf("Get(%q) = %q, want no value", key, value)
+	} else if want != nil && (!ok || string(value) != string(want)) {
+		t.Errorf("Get(%q) = %q, %v, want %q", key, value, ok, want)
+	}
+}
+
+func TestDiskRecoversTornLog(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+
+	d, err := OpenDisk(dir, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Put(NewItem("a", []byte("1")), NewItem("b", []byte("2"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	complete := d.walSize
+	if err := d.Put(NewItem("c", []byte("3"))); err != nil {
+		t.Fatal(err)
+	}
+	crash(d)
+
+	// Tear the last record.
+	walPath := filepath.Join(dir, walFile)
+	info, err := os.Stat(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(walPath, info.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = OpenDisk(dir, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, d, "a", nil)
+	checkValue(t, d, "b", []byte("2"))
+	checkValue(t, d, "c", nil)
+	if d.walSize != complete {
+		t.Errorf("log is %d bytes after recovery, want the %d bytes of complete records", d.walSize, complete)
+	}
+
+	// Records appended after recovery follow the last complete one.
+	if err := d.Put(NewItem("d", []byte("4"))); err != nil {
+		t.Fatal(err)
+	}
+	crash(d)
+
+	d, err = OpenDisk(dir, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	checkValue(t, d, "b", []byte("2"))
+	checkValue(t, d, "d", []byte("4"))
+	if d.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", d.Len())
+	}
+}
+
+func TestDiskCompaction(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+
+	d, err := OpenDisk(dir, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if err := d.Put(NewItem(key, []byte(key))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if d.walSize != 0 {
+		t.Errorf("log is %d bytes after compaction, want 0", d.walSize)
+	}
+	if info, err := os.Stat(filepath.Join(dir, walFile)); err != nil || info.Size() != 0 {
+		t.Errorf("log file after compaction: %v, %v", info, err)
+	}
+
+	// Writes after the compaction are replayed over the snapshot.
+	if err := d.Put(NewItem("a", []byte("A"))); err != nil {
+		t.Fatal(err)
+	}
+	crash(d)
+
+	d, err = OpenDisk(dir, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, d, "a", []byte("A"))
+	checkValue(t, d, "b", nil)
+	checkValue(t, d, "c", []byte("c"))
+
+	// Close compacts, reopening reads the snapshot alone.
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	d, err = OpenDisk(dir, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if d.walSize != 0 || d.Len() != 2 {
+		t.Errorf("reopened with a %d byte log and %d keys, want 0 and 2", d.walSize, d.Len())
+	}
+	checkValue(t, d, "a", []byte("A"))
+}
+
+func TestDiskRejectsCorruptSnapshot(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+
+	d, err := OpenDisk(dir, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Put(NewItem("a", []byte("1")), NewItem("b", []byte("2"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshotPath := filepath.Join(dir, snapshotFile)
+	data, err := ioutil.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := ioutil.WriteFile(snapshotPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, err := OpenDisk(dir, 0, nil); err == nil {
+		d.Close()
+		t.Error("OpenDisk accepted a corrupt snapshot")
+	}
+}
+
+func TestDiskWritesSurviveFailedCompaction(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+
+	logs := &bytes.Buffer{}
+	d, err := OpenDisk(dir, 0, Logging.NewRegistry(logs, Logging.LogfmtFormat, Logging.InfoLevel).Logger("storage"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// A directory in the way of the new snapshot fails compactions.
+	if err := os.Mkdir(filepath.Join(dir, snapshotFile+".tmp"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	large := bytes.Repeat([]byte("x"), minCompactSize)
+	if err := d.Put(NewItem("a", large)); err != nil {
+		t.Fatalf("Put() = %v, want the write to succeed without compaction", err)
+	}
+	if !strings.Contains(logs.String(), "Failed to compact the log") {
+		t.Errorf("failed compaction not logged, logs %q", logs.String())
+	}
+
+	// Writes do not retry until the log grew by another minCompactSize.
+	logs.Reset()
+	if _, err := d.Delete("a"); err != nil {
+		t.Fatalf("Delete() = %v, want the write to succeed without compaction", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("compaction retried on the next write, logs %q", logs.String())
+	}
+	checkValue(t, d, "a", nil)
+
+	if err := os.Remove(filepath.Join(dir, snapshotFile+".tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Put(NewItem("b", large)); err != nil {
+		t.Fatal(err)
+	}
+	if d.walSize != 0 {
+		t.Errorf("log of %d bytes left, want it compacted once the retry size is reached", d.walSize)
+	}
+	checkValue(t, d, "b", large)
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// maxLevel bounds the height of the skip list, enough for 2^24 keys at full speed.
+const maxLevel = 24
+
+// skipNode is an item in the skip list, next holds its successor on every level.
+type skipNode struct {
+	item Item
+	next []*skipNode
+}
+
+// before reports whether item is ordered before (id, key): by ID, then by key for colliding IDs.
+func before(item *Item, id uint64, key string) bool {
+	return item.ID < id || (item.ID == id && item.Key < key)
+}
+
+// index is a skip list of items ordered by ID.
+type index struct {
+	head   skipNode
+	level  int
+	length int
+	rand   *rand.Rand
+}
+
+func newIndex() *index {
+	return &index{
+		head:  skipNode{next: make([]*skipNode, maxLevel)},
+		level: 1,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// seek returns the first node not ordered before (id, key). If update is not
+// nil, it is filled with the last node before (id, key) on every level.
+func (ix *index) seek(id uint64, key string, update []*skipNode) *skipNode {
+	node := &ix.head
+	for level := ix.level - 1; level >= 0; level-- {
+		for node.next[level] != nil && before(&node.next[level].item, id, key) {
+			node = node.next[level]
+		}
+		if update != nil {
+			update[level] = node
+		}
+	}
+	return node.next[0]
+}
+
+func (ix *index) get(key string) *skipNode {
+	id := NewItem(key, nil).ID
+	node := ix.seek(id, key, nil)
+	if node != nil && node.item.ID == id && node.item.Key == key {
+		return node
+	}
+	return nil
+}
+
+func (ix *index) put(item Item) {
+	update := make([]*skipNode, maxLevel)
+	node := ix.seek(item.ID, item.Key, update)
+	if node != nil && node.item.ID == item.ID && node.item.Key == item.Key {
+		node.item.Value = item.Value
+		return
+	}
+
+	level := 1
+	for level < maxLevel && ix.rand.Intn(4) == 0 {
+		level++
+	}
+	for ; ix.level < level; ix.level++ {
+		update[ix.level] = &ix.head
+	}
+
+	node = &skipNode{item: item, next: make([]*skipNode, level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+	ix.length++
+}
+
+func (ix *index) remove(key string) bool {
+	id := NewItem(key, nil).ID
+	update := make([]*skipNode, maxLevel)
+	node := ix.seek(id, key, update)
+	if node == nil || node.item.ID != id || node.item.Key != key {
+		return false
+	}
+
+	for i := 0; i < len(node.next); i++ {
+		update[i].next[i] = node.next[i]
+	}
+	for ix.level > 1 && ix.head.next[ix.level-1] == nil {
+		ix.level--
+	}
+	ix.length--
+	return true
+}
+
+// ascend calls fn for the items with IDs in [from, to] in order, until fn returns false.
+func (ix *index) ascend(from uint64, to uint64, fn func(item Item) bool) bool {
+	for node := ix.seek(from, "", nil); node != nil && node.item.ID <= to; node = node.next[0] {
+		if !fn(node.item) {
+			return false
+		}
+	}
+	return true
+}
+
+// scan calls fn for the items in the ring interval (from, to], see Engine.Range.
+func (ix *index) scan(from uint64, to uint64, fn func(item Item) bool) {
+	if from < to {
+		ix.ascend(from+1, to, fn)
+		return
+	}
+
+	// The interval wraps around zero.
+	if from < math.MaxUint64 && !ix.ascend(from+1, math.MaxUint64, fn) {
+		return
+	}
+	ix.ascend(0, to, fn)
+}
+
+// Memory is an Engine holding the keys in memory.
+type Memory struct {
+	mu sync.RWMutex
+	ix *index
+}
+
+// NewMemory creates an empty Memory engine.
+func NewMemory() *Memory {
+	return &Memory{ix: newIndex()}
+}
+
+// OpenMemory is the Opener of Memory engines.
+func OpenMemory(id uint64) (Engine, error) {
+	return NewMemory(), nil
+}
+
+func (m *Memory) Get(key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	node := m.ix.get(key)
+	if node == nil {
+		return nil, false, nil
+	}
+	return node.item.Value, true, nil
+}
+
+func (m *Memory) Put(items ...Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, item := range items {
+		m.ix.put(NewItem(item.Key, item.Value))
+	}
+	return nil
+}
+
+func (m *Memory) Delete(keys ...string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for _, key := range keys {
+		if m.ix.remove(key) {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (m *Memory) Range(from uint64, to uint64, fn func(item Item) bool) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.ix.scan(from, to, fn)
+	return nil
+}
+
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.ix.length
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+)
+
+// ringRange returns the items with IDs in the ring interval (from, to] in ring order.
+func ringRange(items []Item, from uint64, to uint64) []Item {
+	within := make([]Item, 0, len(items))
+	for _, item := range items {
+		switch {
+		case from == to, from < to && item.ID > from && item.ID <= to, from > to && (item.ID > from || item.ID <= to):
+			within = append(within, item)
+		}
+	}
+	sort.Slice(within, func(i, j int) bool {
+		return within[i].ID-from-1 < within[j].ID-from-1
+	})
+	return within
+}
+
+func TestRangeWrapsAroundTheRing(t *testing.T) {
+	m := NewMemory()
+	items := make([]Item, 0, 200)
+	for i := 0; i < 200; i++ {
+		item := NewItem(fmt.Sprintf("key-%d", i), []byte{byte(i)})
+		items = append(items, item)
+		m.Put(item)
+	}
+
+	quarter := uint64(math.MaxUint64 / 4)
+	tests := []struct {
+		name     string
+		from, to uint64
+	}{
+		{"inside", quarter, 2 * quarter},
+		{"wrapping", 3 * quarter, quarter},
+		{"whole ring", 2 * quarter, 2 * quarter},
+		{"whole ring from zero", 0, 0},
+		{"from the top", math.MaxUint64, quarter},
+		{"to the top", 3 * quarter, math.MaxUint64},
+		{"on an item", items[0].ID, items[0].ID + quarter},
+	}
+	for _, test := range tests {
+		var got []Item
+		m.Range(test.from, test.to, func(item Item) bool {
+			got = append(got, item)
+			return true
+		})
+		want := ringRange(items, test.from, test.to)
+		if len(got) != len(want) {
+			t.Errorf("%s: Range(%d, %d) returned %d items, want %d", test.name, test.from, test.to, len(got), len(want))
+			continue
+		}
+		for i := range want {
+			if got[i].Key != want[i].Key {
+				t.Errorf("%s: item %d is %q, want %q", test.name, i, got[i].Key, want[i].Key)
+				break
+			}
+		}
+	}
+}
+
+func TestRangeStops(t *testing.T) {
+	m := NewMemory()
+	for i := 0; i < 50; i++ {
+		m.Put(NewItem(fmt.Sprintf("key-%d", i), nil))
+	}
+
+	calls := 0
+	m.Range(12345, 12345, func(item Item) bool {
+		calls++
+		return calls < 10
+	})
+	if calls != 10 {
+		t.Errorf("Range called fn %d times after it returned false, want 10", calls)
+	}
+}
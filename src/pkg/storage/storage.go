@@ -0,0 +1,42 @@
+package storage
+
+// Storage engines for the keys of a VNode.
+// Engines order keys by their ID on the ring, so the keys of a range of the
+// ring, which move together when VNodes join and leave, are read by a scan.
+
+import (
+	Hash "github.com/arush15june/chord-golang/src/pkg/hash"
+)
+
+// Item is a key, its ID on the ring and its value.
+type Item struct {
+	ID    uint64
+	Key   string
+	Value []byte
+}
+
+// NewItem returns the Item of key and value.
+func NewItem(key string, value []byte) Item {
+	return Item{ID: Hash.Sum([]byte(key)), Key: key, Value: value}
+}
+
+// Engine stores the keys of one VNode. Engines are safe for concurrent use.
+type Engine interface {
+	// Get returns the value of key and whether it is stored.
+	Get(key string) ([]byte, bool, error)
+	// Put stores items, replacing the values of stored keys.
+	Put(items ...Item) error
+	// Delete removes keys and returns the number of keys which were stored.
+	Delete(keys ...string) (int, error)
+	// Range calls fn for the items with IDs in the ring interval (from, to] in
+	// ring order, the whole ring if from equals to, until fn returns false.
+	// fn must not call the Engine.
+	Range(from uint64, to uint64, fn func(item Item) bool) error
+	// Len returns the number of keys stored.
+	Len() int
+	// Close releases the resources of the Engine.
+	Close() error
+}
+
+// Opener opens the Engine of the VNode with the given ID.
+type Opener func(id uint64) (Engine, error)
@@ -0,0 +1,71 @@
+package topology
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteDOT writes the topology as a Graphviz digraph. Successor edges are
+// solid, predecessor edges dashed and finger edges dotted, VNodes with
+// issues are drawn in red.
+func (t *Topology) WriteDOT(w io.Writer) error {
+	ew := &errWriter{w: w}
+
+	ew.printf("digraph ring {\n")
+	ew.printf("\tlabel=%s;\n", strconv.Quote(t.summary()))
+	ew.printf("\tnode [shape=box, fontname=monospace];\n")
+
+	for _, info := range t.VNodes {
+		color := "black"
+		if t.HasIssues(info.Hostname) {
+			color = "red"
+		}
+		ew.printf("\t%s [label=%s, color=%s];\n", strconv.Quote(info.Hostname), strconv.Quote(fmt.Sprintf("%s\n%d", info.Hostname, info.ID)), color)
+	}
+
+	for _, info := range t.VNodes {
+		successor := ""
+		if len(info.Successors) > 0 {
+			successor = info.Successors[0]
+			ew.printf("\t%s -> %s [label=succ];\n", strconv.Quote(info.Hostname), strconv.Quote(successor))
+		}
+		if info.Predecessor != "" {
+			ew.printf("\t%s -> %s [style=dashed, color=gray, label=pred];\n", strconv.Quote(info.Hostname), strconv.Quote(info.Predecessor))
+		}
+
+		seen := map[string]bool{info.Hostname: true, successor: true}
+		for _, finger := range info.Fingers {
+			if finger == "" || seen[finger] {
+				continue
+			}
+			seen[finger] = true
+			ew.printf("\t%s -> %s [style=dotted, color=blue];\n", strconv.Quote(info.Hostname), strconv.Quote(finger))
+		}
+	}
+
+	ew.printf("}\n")
+	return ew.err
+}
+
+// summary describes the walk in one line.
+func (t *Topology) summary() string {
+	state := "complete"
+	if !t.Complete {
+		state = "incomplete"
+	}
+	return fmt.Sprintf("%d VNodes from %s, %s, %d issues", len(t.VNodes), t.Start, state, len(t.Issues))
+}
+
+// errWriter keeps the first error of a series of writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
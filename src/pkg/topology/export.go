@@ -0,0 +1,40 @@
+package topology
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// contentTypes maps the export formats to their media types.
+var contentTypes = map[string]string{
+	"json": "application/json",
+	"dot":  "text/vnd.graphviz",
+	"svg":  "image/svg+xml",
+}
+
+// ContentType returns the media type of an export format, empty for unknown formats.
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Write writes the topology in format, one of "json", "dot" or "svg".
+func (t *Topology) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		return t.WriteJSON(w)
+	case "dot":
+		return t.WriteDOT(w)
+	case "svg":
+		return t.WriteSVG(w)
+	default:
+		return fmt.Errorf("topology: unknown format %q, use json, dot or svg", format)
+	}
+}
+
+// WriteJSON writes the topology as indented JSON.
+func (t *Topology) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t)
+}
@@ -0,0 +1,70 @@
+package topology
+
+import (
+	"html"
+	"io"
+	"math"
+)
+
+const (
+	svgSize   = 800
+	svgRadius = 300
+)
+
+// position returns the coordinates of an ID on the ring diagram,
+// with ID 0 at the top and IDs increasing clockwise.
+func position(id uint64, radius float64) (float64, float64) {
+	angle := float64(id)/math.Exp2(64)*2*math.Pi - math.Pi/2
+	return svgSize/2 + radius*math.Cos(angle), svgSize/2 + radius*math.Sin(angle)
+}
+
+// WriteSVG writes the topology as a ring diagram. VNodes are placed on a
+// circle by ID with arrows to their successors, VNodes with issues are drawn in red.
+func (t *Topology) WriteSVG(w io.Writer) error {
+	ew := &errWriter{w: w}
+
+	ew.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="11">`+"\n", svgSize, svgSize, svgSize, svgSize)
+	ew.printf(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto"><path d="M 0 0 L 10 5 L 0 10 z" fill="#555"/></marker></defs>` + "\n")
+	ew.printf(`<text x="10" y="20">%s</text>`+"\n", html.EscapeString(t.summary()))
+	ew.printf(`<circle cx="%d" cy="%d" r="%d" fill="none" stroke="#ddd"/>`+"\n", svgSize/2, svgSize/2, svgRadius)
+
+	ids := make(map[string]uint64, len(t.VNodes))
+	for _, info := range t.VNodes {
+		ids[info.Hostname] = info.ID
+	}
+
+	for _, info := range t.VNodes {
+		if len(info.Successors) == 0 {
+			continue
+		}
+		id, ok := ids[info.Successors[0]]
+		if !ok || info.Successors[0] == info.Hostname {
+			continue
+		}
+		x1, y1 := position(info.ID, svgRadius)
+		x2, y2 := position(id, svgRadius)
+		ew.printf(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#555" marker-end="url(#arrow)"/>`+"\n", x1, y1, x2, y2)
+	}
+
+	for _, info := range t.VNodes {
+		color := "#333"
+		if t.HasIssues(info.Hostname) {
+			color = "red"
+		}
+		x, y := position(info.ID, svgRadius)
+		lx, ly := position(info.ID, svgRadius+20)
+		anchor := "start"
+		if lx < svgSize/2 {
+			anchor = "end"
+		}
+		ew.printf(`<circle cx="%.1f" cy="%.1f" r="5" fill="%s"><title>%d</title></circle>`+"\n", x, y, color, info.ID)
+		ew.printf(`<text x="%.1f" y="%.1f" text-anchor="%s" fill="%s">%s</text>`+"\n", lx, ly, anchor, color, html.EscapeString(info.Hostname))
+	}
+
+	for i, issue := range t.Issues {
+		ew.printf(`<text x="10" y="%d" fill="red">%s: %s %s</text>`+"\n", svgSize-10-14*(len(t.Issues)-1-i), html.EscapeString(issue.Kind), html.EscapeString(issue.VNode), html.EscapeString(issue.Detail))
+	}
+
+	ew.printf("</svg>\n")
+	return ew.err
+}
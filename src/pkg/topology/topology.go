@@ -0,0 +1,164 @@
+package topology
+
+// Walks the ring along successors and checks the routing state of the VNodes
+// for inconsistencies.
+
+import (
+	"fmt"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// DefaultLimit is the default maximum number of VNodes visited by a walk.
+const DefaultLimit = 4096
+
+// Kinds of inconsistencies found by a walk.
+const (
+	// PredecessorMismatch is reported when a VNode's predecessor is not the VNode it succeeds.
+	PredecessorMismatch = "predecessor-mismatch"
+	// Loop is reported when the successors lead back to a VNode other than the start.
+	Loop = "loop"
+	// Gap is reported when a successor is unreachable or missing.
+	Gap = "gap"
+	// Wrap is reported when the walk passes the top of the identifier space more than once,
+	// the successors are out of order.
+	Wrap = "wrap"
+	// Stray is reported when a VNode refers to a VNode which is not on the walked ring.
+	Stray = "stray"
+	// Truncated is reported when the walk stopped at its limit.
+	Truncated = "truncated"
+)
+
+// Issue is an inconsistency in the routing state of a VNode.
+type Issue struct {
+	Kind string `json:"kind"`
+	// VNode is the hostname of the VNode the issue was found at.
+	VNode  string `json:"vnode"`
+	Detail string `json:"detail"`
+}
+
+// Topology is the result of a walk along the successors of the ring.
+type Topology struct {
+	// Start is the hostname of the VNode the walk started from.
+	Start string `json:"start"`
+	// Complete is set if the successors led back to the start.
+	Complete bool `json:"complete"`
+	// VNodes are the VNodes visited, in walk order.
+	VNodes []VNode.Info `json:"vnodes"`
+	// Issues are the inconsistencies found.
+	Issues []Issue `json:"issues"`
+}
+
+func (t *Topology) addIssue(kind string, vnode string, format string, args ...interface{}) {
+	t.Issues = append(t.Issues, Issue{Kind: kind, VNode: vnode, Detail: fmt.Sprintf(format, args...)})
+}
+
+// HasIssues reports whether the VNode has any issues.
+func (t *Topology) HasIssues(hostname string) bool {
+	for _, issue := range t.Issues {
+		if issue.VNode == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk follows the successors from the VNode at start until it returns to
+// start, visiting at most limit VNodes. Unreachable successors are skipped
+// using the successor list. It fails only if start is unreachable.
+func Walk(transport *Chord.Transport, start string, limit int) (*Topology, error) {
+	return walk(func(hostname string) (*VNode.Info, error) {
+		return transport.Remote(hostname).Info()
+	}, start, limit)
+}
+
+// walk walks the ring, fetching the routing state of the VNodes with info.
+func walk(info func(hostname string) (*VNode.Info, error), start string, limit int) (*Topology, error) {
+	t := &Topology{Start: start, VNodes: []VNode.Info{}, Issues: []Issue{}}
+	visited := make(map[string]bool)
+
+	candidates := []string{start}
+	previous := ""
+	for len(candidates) > 0 {
+		current := candidates[0]
+		candidates = candidates[1:]
+
+		if current == start && previous != "" {
+			t.Complete = true
+			break
+		}
+		if visited[current] {
+			t.addIssue(Loop, previous, "successor %s was visited before, the walk did not return to %s", current, start)
+			break
+		}
+		if len(t.VNodes) >= limit {
+			t.addIssue(Truncated, previous, "walk stopped after %d VNodes", limit)
+			break
+		}
+
+		state, err := info(current)
+		if err != nil {
+			if previous == "" {
+				return nil, err
+			}
+			t.addIssue(Gap, previous, "successor %s is unreachable: %v", current, err)
+			continue
+		}
+
+		visited[current] = true
+		t.VNodes = append(t.VNodes, *state)
+		previous = current
+
+		if len(state.Successors) == 0 {
+			t.addIssue(Gap, current, "VNode has no successor")
+			break
+		}
+		candidates = state.Successors
+	}
+
+	if !t.Complete && len(candidates) == 0 && previous != "" && !t.HasIssues(previous) {
+		t.addIssue(Gap, previous, "no reachable successor")
+	}
+
+	t.check(visited)
+	return t, nil
+}
+
+// check records the inconsistencies between the visited VNodes.
+func (t *Topology) check(visited map[string]bool) {
+	wraps := 0
+	for i, info := range t.VNodes {
+		var next *VNode.Info
+		if i+1 < len(t.VNodes) {
+			next = &t.VNodes[i+1]
+		} else if t.Complete {
+			next = &t.VNodes[0]
+		}
+
+		if next != nil {
+			if next.Predecessor != info.Hostname && len(info.Successors) > 0 && info.Successors[0] == next.Hostname {
+				t.addIssue(PredecessorMismatch, next.Hostname, "predecessor is %q, expected %s", next.Predecessor, info.Hostname)
+			}
+			if next.ID <= info.ID {
+				wraps++
+			}
+		}
+
+		if !t.Complete {
+			continue
+		}
+		if info.Predecessor != "" && !visited[info.Predecessor] {
+			t.addIssue(Stray, info.Hostname, "predecessor %s is not on the ring", info.Predecessor)
+		}
+		for _, successor := range info.Successors {
+			if !visited[successor] {
+				t.addIssue(Stray, info.Hostname, "successor %s is not on the ring", successor)
+			}
+		}
+	}
+
+	if wraps > 1 {
+		t.addIssue(Wrap, t.Start, "successors pass the top of the identifier space %d times", wraps)
+	}
+}
@@ -0,0 +1,113 @@
+package topology
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	VNode "github.com/arush15june/chord-golang/src/pkg/vnode"
+)
+
+// ring maps hostnames to the routing state of VNodes, missing VNodes are unreachable.
+type ring map[string]*VNode.Info
+
+// vnode adds the VNode at hostname with id, predecessor and successors.
+func (r ring) vnode(hostname string, id uint64, predecessor string, successors ...string) ring {
+	r[hostname] = &VNode.Info{ID: id, Hostname: hostname, Predecessor: predecessor, Successors: successors}
+	return r
+}
+
+func (r ring) info(hostname string) (*VNode.Info, error) {
+	info, ok := r[hostname]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return info, nil
+}
+
+// issues returns the sorted issues of t as kind@vnode.
+func issues(t *Topology) []string {
+	result := make([]string, 0, len(t.Issues))
+	for _, issue := range t.Issues {
+		result = append(result, issue.Kind+"@"+issue.VNode)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		name         string
+		ring         ring
+		limit        int
+		wantComplete bool
+		wantVNodes   int
+		wantIssues   []string
+	}{
+		{
+			"consistent",
+			ring{}.vnode("a", 10, "c", "b", "c").vnode("b", 20, "a", "c", "a").vnode("c", 30, "b", "a", "b"),
+			DefaultLimit, true, 3, []string{},
+		},
+		{
+			"unreachable successor",
+			ring{}.vnode("a", 10, "c", "b", "c").vnode("c", 30, "b", "a"),
+			DefaultLimit, true, 2, []string{Gap + "@a", Stray + "@a", Stray + "@c"},
+		},
+		{
+			"loop",
+			ring{}.vnode("a", 10, "c", "b").vnode("b", 20, "a", "c").vnode("c", 30, "b", "b"),
+			DefaultLimit, false, 3, []string{Loop + "@c"},
+		},
+		{
+			"predecessor mismatch",
+			ring{}.vnode("a", 10, "c", "b").vnode("b", 20, "c", "c").vnode("c", 30, "b", "a"),
+			DefaultLimit, true, 3, []string{PredecessorMismatch + "@b"},
+		},
+		{
+			"stray predecessor",
+			ring{}.vnode("a", 10, "x", "b").vnode("b", 20, "a", "a"),
+			DefaultLimit, true, 2, []string{PredecessorMismatch + "@a", Stray + "@a"},
+		},
+		{
+			"successors out of order",
+			ring{}.vnode("a", 10, "b", "c").vnode("c", 30, "a", "b").vnode("b", 20, "c", "a"),
+			DefaultLimit, true, 3, []string{Wrap + "@a"},
+		},
+		{
+			"truncated",
+			ring{}.vnode("a", 10, "c", "b").vnode("b", 20, "a", "c").vnode("c", 30, "b", "a"),
+			2, false, 2, []string{Truncated + "@b"},
+		},
+		{
+			"no successor",
+			ring{}.vnode("a", 10, "b", "b").vnode("b", 20, "a"),
+			DefaultLimit, false, 2, []string{Gap + "@b"},
+		},
+		{
+			"no reachable successor",
+			ring{}.vnode("a", 10, "b", "b", "c"),
+			DefaultLimit, false, 1, []string{Gap + "@a", Gap + "@a"},
+		},
+	}
+	for _, test := range tests {
+		topology, err := walk(test.ring.info, "a", test.limit)
+		if err != nil {
+			t.Errorf("%s: walk() error = %v", test.name, err)
+			continue
+		}
+		if topology.Complete != test.wantComplete || len(topology.VNodes) != test.wantVNodes {
+			t.Errorf("%s: complete %v after %d VNodes, want %v after %d", test.name, topology.Complete, len(topology.VNodes), test.wantComplete, test.wantVNodes)
+		}
+		if got := issues(topology); !reflect.DeepEqual(got, test.wantIssues) {
+			t.Errorf("%s: issues %q, want %q", test.name, got, test.wantIssues)
+		}
+	}
+}
+
+func TestWalkFailsOnUnreachableStart(t *testing.T) {
+	if _, err := walk(ring{}.info, "a", DefaultLimit); err == nil {
+		t.Error("walk() from an unreachable VNode succeeded")
+	}
+}
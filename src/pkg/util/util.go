@@ -2,17 +2,28 @@ package util
 
 import (
 	"math/rand"
+	"time"
 )
 
-// IsBetweenID checks if the cmp lies between low and high (exclusive of low, inclusive of high).
+// IsBetweenID checks if the cmp lies between low and high (exclusive of low, inclusive of high)
+// going clockwise around the ring, the interval wraps around zero when low >= high.
 func IsBetweenID(cmp uint64, low uint64, high uint64) bool {
-	if cmp > low && cmp <= high || (cmp == high) {
-		return true
+	if low < high {
+		return cmp > low && cmp <= high
 	}
-	return false
+	return cmp > low || cmp <= high
 }
 
 // GetRandomBetween returns a random integer value between low and high.
 func GetRandomBetween(low int, high int) int {
 	return rand.Intn(high-low) + low
 }
+
+// GetRandomDurationBetween returns a random duration between low and high.
+// It returns low if high is not greater than low.
+func GetRandomDurationBetween(low time.Duration, high time.Duration) time.Duration {
+	if high <= low {
+		return low
+	}
+	return time.Duration(rand.Int63n(int64(high-low))) + low
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIsBetweenID(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmp       uint64
+		low, high uint64
+		want      bool
+	}{
+		{"inside", 5, 1, 10, true},
+		{"below", 0, 1, 10, false},
+		{"above", 11, 1, 10, false},
+		{"low is exclusive", 1, 1, 10, false},
+		{"high is inclusive", 10, 1, 10, true},
+		{"wraps past the top", math.MaxUint64, 10, 1, true},
+		{"wraps to zero", 0, 10, 1, true},
+		{"wraps to high", 1, 10, 1, true},
+		{"outside wrapped interval", 5, 10, 1, false},
+		{"low is exclusive when wrapping", 10, 10, 1, false},
+		{"low equals high is the whole ring", 3, 7, 7, true},
+		{"low equals high includes high", 7, 7, 7, true},
+		{"low equals high includes zero", 0, 7, 7, true},
+	}
+	for _, test := range tests {
+		if got := IsBetweenID(test.cmp, test.low, test.high); got != test.want {
+			t.Errorf("%s: IsBetweenID(%d, %d, %d) = %v, want %v", test.name, test.cmp, test.low, test.high, got, test.want)
+		}
+	}
+}
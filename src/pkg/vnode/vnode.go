@@ -22,6 +22,22 @@ type VNodeProtocol interface {
 	// GetPredecessor returns the predecessor VNode.
 	GetPredecessor() (VNodeProtocol, error)
 
+	// NotifyLeave informs the VNode that a VNode is leaving,
+	// along with the leaving VNode's predecessor and successor.
+	NotifyLeave(leaving VNodeProtocol, predecessor VNodeProtocol, successor VNodeProtocol) error
+
+	// Route forwards an application message hop by hop towards the owner of its key.
+	Route(*Message) error
+
+	// Deliver hands an application message to the VNode, which owns its key.
+	Deliver(*Message) error
+
+	// Broadcast hands an application message to the VNode and the VNodes up to the message's limit.
+	Broadcast(*BroadcastMessage) (*BroadcastReply, error)
+
+	// Info returns the routing state of the VNode.
+	Info() (*Info, error)
+
 	// IsBetweenNodes
 	IsBetweenNodes(VNodeProtocol, VNodeProtocol) bool
 
@@ -32,6 +48,70 @@ type VNodeProtocol interface {
 	Hostname() string
 }
 
+// Message is an opaque application payload routed to the owner of a key.
+type Message struct {
+	// Application names the application handling the message on every hop.
+	Application string
+	// Key is the identifier the message is routed to.
+	Key uint64
+	// Payload is the application data.
+	Payload []byte
+	// Origin is the hostname of the VNode the message was routed from.
+	Origin string
+	// Hops counts the VNodes the message has been forwarded through.
+	Hops int
+}
+
+// Info describes the routing state of a VNode.
+type Info struct {
+	ID       uint64 `json:"id"`
+	Hostname string `json:"hostname"`
+	// Predecessor is the hostname of the predecessor, empty if unknown.
+	Predecessor string `json:"predecessor"`
+	// Successors are the hostnames of the successor list, closest first.
+	Successors []string `json:"successors"`
+	// Fingers are the hostnames of the finger table, empty for unset fingers.
+	Fingers []string `json:"fingers"`
+}
+
+// BroadcastMessage is an opaque application payload sent to every VNode of the ring.
+type BroadcastMessage struct {
+	// Application names the application handling the message on every VNode.
+	Application string
+	// Payload is the application data.
+	Payload []byte
+	// Origin is the hostname of the VNode the broadcast started from.
+	Origin string
+	// Limit is the exclusive end of the identifier range the receiving VNode covers.
+	Limit uint64
+	// Depth counts the rounds the message has been forwarded in.
+	Depth int
+}
+
+// BroadcastReply aggregates the replies of the VNodes a broadcast reached.
+type BroadcastReply struct {
+	// Acks counts the VNodes which received the message.
+	Acks int
+	// Failed counts the VNodes the message could not be forwarded to,
+	// the ranges they cover have not been reached.
+	Failed int
+	// Payload is the aggregated application reply.
+	Payload []byte
+}
+
+// Identity is the signed claim of a VNode to its hostname. The VNode's ID is
+// derived from the public key of the certificate and the index, the zero
+// Identity stands for a VNode whose ID is the hash of its hostname.
+type Identity struct {
+	Hostname string
+	// Index is the index of the VNode among the VNodes holding the certificate.
+	Index int
+	// Certificate is the DER certificate of the VNode's key.
+	Certificate []byte
+	// Signature is the signature of the hostname and index by the certificate's key.
+	Signature []byte
+}
+
 // VNode is a virtual node running the chord protocol.
 type VNode struct {
 	// Hostname is the hostname of the VNode.
@@ -57,6 +137,21 @@ func (v *VNode) CheckPredecessor() error {
 func (v *VNode) GetPredecessor() (*VNodeProtocol, error) {
 	return nil, nil
 }
+func (v *VNode) NotifyLeave(*VNodeProtocol, *VNodeProtocol, *VNodeProtocol) error {
+	return nil
+}
+func (v *VNode) Route(*Message) error {
+	return nil
+}
+func (v *VNode) Deliver(*Message) error {
+	return nil
+}
+func (v *VNode) Broadcast(*BroadcastMessage) (*BroadcastReply, error) {
+	return nil, nil
+}
+func (v *VNode) Info() (*Info, error) {
+	return nil, nil
+}
 func (v *VNode) IsBetweenNodes(*VNodeProtocol, *VNodeProtocol) bool {
 	return true
 }
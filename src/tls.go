@@ -0,0 +1,25 @@
+package main
+
+import (
+	"crypto/tls"
+
+	Chord "github.com/arush15june/chord-golang/src/pkg/chord"
+)
+
+// rpcTLS secures the RPC traffic of the ring, nil if TLS is disabled.
+var rpcTLS *tls.Config
+
+// InitTLS loads the TLS configuration of the RPC transport if a certificate or trust root is configured.
+func InitTLS() error {
+	if config.TLSCert == "" && config.TLSCA == "" {
+		return nil
+	}
+
+	tlsConfig, err := Chord.LoadTLSConfig(config.TLSCert, config.TLSKey, config.TLSCA, config.MutualTLS)
+	if err != nil {
+		return err
+	}
+
+	rpcTLS = tlsConfig
+	return nil
+}